@@ -1,25 +1,202 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"goMud/internal/gmsl"
 	"goMud/internal/gmsl/compiler"
-	"goMud/internal/gmsl/lexer"
-	"goMud/internal/gmsl/parser"
+	"goMud/internal/logging"
+	"goMud/internal/mudtest"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 )
 
+type mode func(path string) *gmsl.Result
+
+var modes = map[string]mode{
+	"check":  checkMode,
+	"ast":    astMode,
+	"asm":    asmMode,
+	"build":  buildMode,
+	"tokens": tokensMode,
+	"test":   testMode,
+}
+
+var (
+	jsonOutput = flag.Bool("json", false, "print diagnostics as a JSON array on stdout")
+	verbose    = flag.Bool("v", false, "print lexer, parser and compiler logs, in test mode list every test")
+	mudlibPath = flag.String("mudlib", "mudlib", "mudlib root classes are loaded from in test mode")
+	runPattern = flag.String("run", "", "in test mode run only tests matching the regular expression")
+)
+
+var testsFailed = false
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: compiler [flags] check|ast|asm|build|tokens|test [file or directory ...]")
+	fmt.Fprintln(os.Stderr, "Directories are searched recursively for "+compiler.SourceExtension+" files, default is mudlib.")
+	flag.PrintDefaults()
+}
+
 func main() {
-	// read mudlib/player_handler.gms into string
-	b, err := os.ReadFile("mudlib/player_handler.gms")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := modes[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "unknown mode", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	if !*verbose || flag.Arg(0) == "test" {
+		log.SetOutput(io.Discard)
+		logging.SetAllLevels(slog.LevelWarn)
+	} else {
+		logging.SetAllLevels(logging.LevelTrace)
+	}
+
+	args := flag.Args()[1:]
+	if len(args) == 0 {
+		args = []string{"mudlib"}
+	}
+
+	diagnostics := make([]gmsl.Diagnostic, 0)
+	for _, arg := range args {
+		files, err := collectFiles(arg)
+		if err != nil {
+			diagnostics = append(diagnostics, gmsl.Diagnostic{File: arg, Severity: gmsl.SeverityError, Message: err.Error()})
+		}
+		for _, file := range files {
+			diagnostics = append(diagnostics, run(file).Diagnostics...)
+		}
+	}
+
+	report(diagnostics)
+	if len(diagnostics) > 0 || testsFailed {
+		os.Exit(1)
+	}
+}
+
+func collectFiles(arg string) ([]string, error) {
+	info, err := os.Stat(arg)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{arg}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, compiler.SourceExtension) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func report(diagnostics []gmsl.Diagnostic) {
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, "Error encoding diagnostics:", err)
+		}
 		return
 	}
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+}
+
+func checkMode(path string) *gmsl.Result {
+	return gmsl.CompileFile(path)
+}
+
+func astMode(path string) *gmsl.Result {
+	result := gmsl.ParseFile(path)
+	if !result.HasErrors() {
+		fmt.Print(result.Class.PrettyPrint(0))
+	}
+	return result
+}
+
+func asmMode(path string) *gmsl.Result {
+	result := gmsl.CompileFile(path)
+	if !result.HasErrors() {
+		fmt.Println("; " + path)
+		fmt.Print(result.Assembly)
+	}
+	return result
+}
+
+func buildMode(path string) *gmsl.Result {
+	result := gmsl.CompileFile(path)
+	if result.HasErrors() {
+		return result
+	}
+	if err := writeBytecode(compiler.BytecodePath(path), result.Assembly); err != nil {
+		result.Diagnostics = append(result.Diagnostics, gmsl.Diagnostic{File: path, Severity: gmsl.SeverityError, Message: err.Error()})
+	}
+	return result
+}
+
+func tokensMode(path string) *gmsl.Result {
+	result := gmsl.ReadFile(path)
+	if result.HasErrors() {
+		return result
+	}
+	for _, token := range result.Tokens() {
+		fmt.Printf("%s:%s\t%s\t%q\n", path, token.GetPosition(), token.Typ, token.GetRawValue())
+	}
+	return result
+}
 
-	l := lexer.NewLexer(string(b))
+func testMode(path string) *gmsl.Result {
+	result := &gmsl.Result{File: path}
+	if !mudtest.IsTestFile(path) {
+		return result
+	}
+	run, err := regexp.Compile(*runPattern)
+	if err != nil {
+		result.Diagnostics = append(result.Diagnostics, gmsl.Diagnostic{File: path, Severity: gmsl.SeverityError, Message: "invalid -run: " + err.Error()})
+		return result
+	}
 
-	p := parser.NewParser(l)
-	ast := p.Parse()
-	aout := compiler.NewCompiler(ast).Compile()
-	fmt.Println(aout)
+	start := time.Now()
+	results, err := mudtest.RunFile(*mudlibPath, path, run.MatchString)
+	if err != nil {
+		fmt.Printf("FAIL\t%s [setup failed]\n", path)
+		result.Diagnostics = append(result.Diagnostics, gmsl.Diagnostic{File: path, Severity: gmsl.SeverityError, Message: err.Error()})
+		return result
+	}
+	if !mudtest.Report(os.Stdout, path, results, time.Since(start), *verbose) {
+		testsFailed = true
+	}
+	return result
+}
+
+func writeBytecode(path string, aout *compiler.Assembly) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := aout.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"goMud/internal/logging"
+	"goMud/internal/lsp"
+	"io"
+	"os"
+)
+
+func main() {
+	logFile := flag.String("log", "", "write server logs to this file")
+	flag.Parse()
+
+	logging.SetOutput(io.Discard, false)
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening log file:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		logging.SetOutput(f, false)
+	}
+
+	shutdown, err := lsp.NewServer(os.Stdin, os.Stdout).Serve()
+	if err != nil {
+		logging.Logger(logging.GMSL).Error("Serving failed", "error", err)
+		os.Exit(1)
+	}
+	if !shutdown {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"goMud/internal/logging"
+	"goMud/internal/repl"
+	"goMud/internal/vm"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	room := flag.String("room", "", "mudlib class used as the room context, e.g. locations/room_a")
+	verbose := flag.Bool("v", false, "print compiler logs and trace the VM")
+	flag.Parse()
+	if *verbose {
+		logging.SetAllLevels(logging.LevelTrace)
+	} else {
+		log.SetOutput(io.Discard)
+		logging.SetOutput(io.Discard, false)
+	}
+
+	session := repl.NewSession(vm.GetVirtualMachine(), repl.NewStubContext(os.Stdout, *room))
+
+	fmt.Println("GMSL REPL, :vars lists variables, :quit exits.")
+	scanner := bufio.NewScanner(os.Stdin)
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Print("> ")
+		} else {
+			fmt.Print("... ")
+		}
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		line := scanner.Text()
+
+		if input.Len() == 0 {
+			switch strings.TrimSpace(line) {
+			case "":
+				continue
+			case ":quit":
+				return
+			case ":vars":
+				fmt.Println(strings.Join(session.Variables(), " "))
+				continue
+			}
+		}
+
+		input.WriteString(line)
+		input.WriteString("\n")
+		if session.NeedsMore(input.String()) {
+			continue
+		}
+
+		results, err := session.Eval(input.String())
+		input.Reset()
+		if err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		for _, r := range results {
+			fmt.Println(repl.FormatValue(r))
+		}
+	}
+}
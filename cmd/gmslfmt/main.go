@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"goMud/internal/gmsl/compiler"
+	"goMud/internal/gmsl/format"
+	"goMud/internal/logging"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	write = flag.Bool("w", false, "write result to the source file instead of stdout")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	list  = flag.Bool("l", false, "list files whose formatting differs")
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gmslfmt [flags] [file or directory ...]")
+	fmt.Fprintln(os.Stderr, "Without arguments the source is read from stdin.")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	log.SetOutput(io.Discard)
+	logging.SetOutput(io.Discard, false)
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "gmslfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err == nil {
+			err = processSource("<standard input>", src)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := false
+	for _, arg := range flag.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (path != arg && !strings.HasSuffix(path, compiler.SourceExtension)) {
+				return nil
+			}
+			if err := processFile(path); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func processFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(path, src)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, formatted) {
+		if !*write && !*diff && !*list {
+			os.Stdout.Write(formatted)
+		}
+		return nil
+	}
+
+	if *list {
+		fmt.Println(path)
+	}
+	if *diff {
+		fmt.Print(unifiedDiff(path, string(src), string(formatted)))
+	}
+	if *write {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, formatted, info.Mode().Perm())
+	}
+	if !*diff && !*list {
+		os.Stdout.Write(formatted)
+	}
+	return nil
+}
+
+func processSource(name string, src []byte) error {
+	formatted, err := format.Source(name, src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *list:
+		if !bytes.Equal(src, formatted) {
+			fmt.Println(name)
+		}
+	case *diff:
+		fmt.Print(unifiedDiff(name, string(src), string(formatted)))
+	default:
+		os.Stdout.Write(formatted)
+	}
+	return nil
+}
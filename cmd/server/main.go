@@ -1,10 +1,67 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"goMud/internal/account"
+	"goMud/internal/game"
+	"goMud/internal/logging"
 	"goMud/internal/net"
+	"goMud/internal/vm"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
+const snapshotFile = "world.snapshot"
+
 func main() {
+	flag.BoolVar(&game.WizardEvalEnabled, "wizard-eval", false, "allow wizards to run GMSL with the eval command")
+	flag.BoolVar(&game.WizardDebugEnabled, "wizard-debug", false, "allow wizards to stop and step the VM with the debug command")
+	flag.BoolVar(&game.WizardProfileEnabled, "wizard-profile", false, "allow wizards to profile GMSL code with the profile command")
+	levels := flag.String("log", "info", "log levels, a default and per subsystem overrides, e.g. warn,vm=debug,net=trace")
+	jsonLogs := flag.Bool("log-json", false, "write logs as JSON lines")
+	vmTrace := flag.Bool("vm-trace", false, "log every VM operation, same as -log vm=trace")
+	dataPath := flag.String("data", vm.DefaultDataPath, "directory save files and the world snapshot are kept in")
+	autosave := flag.Duration("autosave", 5*time.Minute, "how often the world is snapshot, 0 only snapshots on shutdown")
+	restore := flag.Bool("restore", true, "restore the world from the last snapshot on boot")
+	flag.DurationVar(&game.LinkDeadTimeout, "linkdead", game.LinkDeadTimeout, "how long a player whose connection dropped stays in the world")
+	flag.Parse()
+
+	logging.SetOutput(os.Stderr, *jsonLogs)
+	if err := logging.ParseLevels(*levels); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid -log:", err)
+		os.Exit(2)
+	}
+	if *vmTrace {
+		logging.SetLevel(logging.VM, logging.LevelTrace)
+	}
+
+	machine := vm.GetVirtualMachine()
+	machine.SetDataPath(*dataPath)
+	game.Accounts = account.NewStore(filepath.Join(*dataPath, "accounts"))
+	snapshotPath := filepath.Join(*dataPath, snapshotFile)
+	machine.SetTransient(game.LoginObjectName)
+	if *restore {
+		if err := machine.RestoreSnapshot(snapshotPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, "Cannot restore the world, move the snapshot away or start with -restore=false:", err)
+			os.Exit(1)
+		}
+	}
+	machine.SetAutosave(snapshotPath, *autosave)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		machine.Shutdown()
+		os.Exit(0)
+	}()
+
 	s := net.NewServer()
 	s.Start()
 }
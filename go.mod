@@ -2,3 +2,4 @@ module goMud
 
 go 1.22rc2
 
+require golang.org/x/crypto v0.31.0
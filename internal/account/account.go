@@ -0,0 +1,151 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"goMud/internal/fsutil"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MinNameLength     = 3
+	MaxNameLength     = 16
+	MinPasswordLength = 6
+	// bcrypt ignores everything after 72 bytes.
+	MaxPasswordLength = 72
+	fileExtension     = ".json"
+)
+
+var (
+	ErrExists          = errors.New("account already exists")
+	ErrInvalidName     = errors.New("invalid account name")
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
+type Account struct {
+	Name         string            `json:"name"`
+	PasswordHash string            `json:"password_hash"`
+	Created      time.Time         `json:"created"`
+	LastLogin    time.Time         `json:"last_login"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	// Wizard lets the account use the eval, debug and profile commands the
+	// server enables. It is only set by editing the account file.
+	Wizard bool `json:"wizard,omitempty"`
+}
+
+// ValidName accepts names of ASCII letters only, they double as file names.
+func ValidName(name string) bool {
+	if len(name) < MinNameLength || len(name) > MaxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func ValidPassword(password string) bool {
+	return len(password) >= MinPasswordLength && len(password) <= MaxPasswordLength
+}
+
+// DisplayName capitalizes a name the way it is shown to other players.
+func DisplayName(name string) string {
+	name = strings.ToLower(name)
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (a *Account) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) == nil
+}
+
+func (a *Account) SetPassword(password string) error {
+	if !ValidPassword(password) {
+		return ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	a.PasswordHash = string(hash)
+	return nil
+}
+
+// Store keeps one JSON file per account in a directory, named after the
+// lower case account name.
+type Store struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) file(name string) string {
+	return filepath.Join(s.path, strings.ToLower(name)+fileExtension)
+}
+
+func (s *Store) Exists(name string) bool {
+	if !ValidName(name) {
+		return false
+	}
+	_, err := os.Stat(s.file(name))
+	return err == nil
+}
+
+func (s *Store) Load(name string) (*Account, error) {
+	if !ValidName(name) {
+		return nil, ErrInvalidName
+	}
+	data, err := os.ReadFile(s.file(name))
+	if err != nil {
+		return nil, err
+	}
+	a := &Account{Attributes: make(map[string]string)}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Create saves a new account, ErrExists when the name is taken.
+func (s *Store) Create(name string, password string) (*Account, error) {
+	if !ValidName(name) {
+		return nil, ErrInvalidName
+	}
+	a := &Account{Name: DisplayName(name), Created: time.Now().UTC(), Attributes: make(map[string]string)}
+	if err := a.SetPassword(password); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Exists(name) {
+		return nil, ErrExists
+	}
+	return a, s.save(a)
+}
+
+func (s *Store) Save(a *Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.save(a)
+}
+
+func (s *Store) save(a *Account) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.file(a.Name), append(data, '\n'), 0o700)
+}
@@ -0,0 +1,48 @@
+package game
+
+import (
+	"goMud/internal/markup"
+	"goMud/internal/vm"
+	"strings"
+)
+
+// ColourAttribute is the account attribute remembering a player turned
+// colour off.
+const ColourAttribute = "colour"
+
+// colour shows or toggles whether the player's lines are coloured, the
+// choice is kept with the account.
+func (h *Handler) colour(machine *vm.VirtualMachine, args string) {
+	switch strings.ToLower(args) {
+	case "":
+	case "on":
+		h.setColour(true)
+	case "off":
+		h.setColour(false)
+	default:
+		h.send("Usage: colour [on|off]")
+		return
+	}
+	if mode := h.terminal.ColourMode(); mode == markup.Plain {
+		h.send("Colour is off.")
+	} else {
+		h.send("Colour is {green}on{/}, in " + mode.String() + ".")
+	}
+}
+
+func (h *Handler) setColour(enabled bool) {
+	h.terminal.SetColour(enabled)
+	if enabled {
+		delete(h.account.Attributes, ColourAttribute)
+	} else {
+		h.account.Attributes[ColourAttribute] = "off"
+	}
+	if err := Accounts.Save(h.account); err != nil {
+		h.logger.Warn("Cannot save account", "account", h.account.Name, "error", err)
+	}
+}
+
+// applyColour turns colour off for a player who asked for that before.
+func (h *Handler) applyColour() {
+	h.terminal.SetColour(h.account.Attributes[ColourAttribute] != "off")
+}
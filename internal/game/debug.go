@@ -0,0 +1,183 @@
+package game
+
+import (
+	"errors"
+	"goMud/internal/repl"
+	"goMud/internal/vm"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const debugCommand = "debug"
+
+// WizardDebugEnabled lets wizards drive the VM debugger. A stopped VM stops
+// the whole world, so like eval it is meant for development servers.
+var WizardDebugEnabled = false
+
+const debugHelp = `debug break <file:line|Function|class.Function>  set a breakpoint
+debug clear <id>                                 remove a breakpoint
+debug breakpoints                                list breakpoints
+debug continue|step|next|out                     resume, step in, over or out
+debug pause                                      stop at the next line run
+debug stack                                      show the call stack
+debug locals [frame]                             show registers and value stack
+debug off                                        detach the debugger`
+
+func (h *Handler) isDebugCommand(line string) bool {
+	return WizardDebugEnabled && (line == debugCommand || strings.HasPrefix(line, debugCommand+" "))
+}
+
+// handleDebugCommand runs on the handler goroutine and never waits for the
+// VM command loop, which is blocked while the debugger has it stopped.
+func (h *Handler) handleDebugCommand(line string) {
+	fields := strings.Fields(strings.TrimPrefix(line, debugCommand))
+	if len(fields) == 0 {
+		h.send(debugHelp)
+		return
+	}
+	if err := h.runDebugCommand(fields[0], fields[1:]); err != nil {
+		h.send("debug: " + err.Error())
+	}
+}
+
+func (h *Handler) runDebugCommand(command string, args []string) error {
+	if command == "break" {
+		if len(args) != 1 {
+			return errors.New("usage: debug break <file:line|Function|class.Function>")
+		}
+		if err := h.attachDebugger(); err != nil {
+			return err
+		}
+		b, err := h.debugger.SetBreakpoint(args[0])
+		if err != nil {
+			return err
+		}
+		h.send("breakpoint " + b.String())
+		return nil
+	}
+	if h.debugger == nil {
+		return errors.New("no breakpoints set, start with debug break")
+	}
+
+	switch command {
+	case "clear":
+		id, err := strconv.Atoi(strings.Join(args, ""))
+		if err != nil || !h.debugger.ClearBreakpoint(id) {
+			return errors.New("no breakpoint " + strings.Join(args, " "))
+		}
+	case "breakpoints":
+		for _, b := range h.debugger.Breakpoints() {
+			h.send(b.String())
+		}
+	case "continue", "c":
+		return h.debugger.Continue()
+	case "step", "s":
+		return h.debugger.StepIn()
+	case "next", "n":
+		return h.debugger.StepOver()
+	case "out":
+		return h.debugger.StepOut()
+	case "pause":
+		h.debugger.Pause()
+	case "stack", "bt":
+		stop := h.debugger.Stopped()
+		if stop == nil {
+			return errors.New("the VM is running")
+		}
+		for i, f := range stop.Frames {
+			h.send("#" + strconv.Itoa(i) + " " + f.String())
+		}
+	case "locals":
+		return h.sendLocals(args)
+	case "off":
+		h.detachDebugger()
+	default:
+		return errors.New("unknown command " + command)
+	}
+	return nil
+}
+
+func (h *Handler) sendLocals(args []string) error {
+	stop := h.debugger.Stopped()
+	if stop == nil {
+		return errors.New("the VM is running")
+	}
+	n := 0
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 0 || n >= len(stop.Frames) {
+			return errors.New("no frame " + args[0])
+		}
+	}
+	frame := stop.Frames[n]
+	for _, r := range frame.Registers {
+		h.send("  " + r.Name + " = " + formatDebugValue(r.Value))
+	}
+	for i, v := range frame.Stack {
+		h.send("  stack[" + strconv.Itoa(i) + "] = " + formatDebugValue(v))
+	}
+	return nil
+}
+
+func formatDebugValue(v vm.Value) string {
+	if v == nil {
+		return "nil"
+	}
+	return repl.FormatValue(v)
+}
+
+// attachDebugger installs a debugger on the VM goroutine. Only one player can
+// debug at a time, the VM has a single debugger.
+func (h *Handler) attachDebugger() error {
+	if h.debugger != nil {
+		return nil
+	}
+	d := vm.NewDebugger(h.reportStop)
+	done := make(chan error)
+	vm.GetCommandChannel() <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+		if machine.GetDebugger() != nil {
+			done <- errors.New("someone else is debugging the VM")
+			return
+		}
+		machine.SetDebugger(d)
+		done <- nil
+	})
+	if err := <-done; err != nil {
+		return err
+	}
+	h.debugger = d
+	return nil
+}
+
+func (h *Handler) detachDebugger() {
+	d := h.debugger
+	h.debugger = nil
+	d.Close()
+	vm.GetCommandChannel() <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+		if machine.GetDebugger() == d {
+			machine.SetDebugger(nil)
+		}
+	})
+}
+
+func (h *Handler) reportStop(stop vm.Stop) {
+	h.send("debug: " + stop.String())
+	if len(stop.Frames) > 0 {
+		if source := sourceLine(stop.Frames[0].File, stop.Frames[0].Line); source != "" {
+			h.send(strconv.Itoa(stop.Frames[0].Line) + ":\t" + source)
+		}
+	}
+}
+
+func sourceLine(file string, line int) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(b), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
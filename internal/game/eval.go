@@ -0,0 +1,33 @@
+package game
+
+import (
+	"goMud/internal/repl"
+	"goMud/internal/vm"
+	"strings"
+)
+
+const evalCommandPrefix = "eval "
+
+// WizardEvalEnabled lets wizards run GMSL with the eval command.
+var WizardEvalEnabled = false
+
+func (h *Handler) isEvalCommand(line string) bool {
+	return WizardEvalEnabled && strings.HasPrefix(line, evalCommandPrefix)
+}
+
+func (h *Handler) newEvalCommand(line string) vm.Command {
+	input := strings.TrimPrefix(line, evalCommandPrefix)
+	return vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+		if h.evalSession == nil {
+			h.evalSession = repl.NewSession(machine, &h.context)
+		}
+		results, err := h.evalSession.Eval(input)
+		if err != nil {
+			h.send("eval error: " + err.Error())
+			return
+		}
+		for _, r := range results {
+			h.send("= " + repl.FormatValue(r))
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"goMud/internal/gmcp"
+	"goMud/internal/vm"
+	"log"
+	"strings"
+)
+
+// GMCPMethod is the method of the player handler that gets the client's
+// GMCP messages, the package name and the value as a <json> object, 0 for
+// a message without one.
+const GMCPMethod = "GMCP"
+
+// handleGMCP keeps track of what the client says about itself and passes
+// every message on to the player handler.
+func (h *Handler) handleGMCP(machine *vm.VirtualMachine, m gmcp.Message) {
+	h.logger.Debug("GMCP received", "package", m.Package)
+	if strings.EqualFold(m.Package, "Core.Hello") {
+		h.logger.Info("GMCP client", "hello", string(m.Data))
+	}
+	h.gmcpSupports.Apply(m)
+	if h.vmHandlerObject == nil || !h.vmHandlerObject.HasMethod(GMCPMethod) {
+		return
+	}
+	var data vm.Value = vm.NewNumberValue(0)
+	if m.Data != nil {
+		o, err := vm.NewJSONObject(m.Data)
+		if err != nil {
+			h.logger.Warn("Cannot decode GMCP", "package", m.Package, "error", err)
+			return
+		}
+		data = *vm.NewObjectValue(o)
+	}
+	if _, err := machine.Call(h.vmHandlerObject, GMCPMethod, []vm.Value{vm.NewStringValue(m.Package), data}, &h.context); err != nil {
+		h.logger.Warn("GMCP handler failed", "package", m.Package, "error", err)
+	}
+}
+
+// sendGMCP sends data, a string, number or <json> object, in a GMCP
+// message. An empty string sends the message without a value.
+func (h *Handler) sendGMCP(name string, data vm.Value) {
+	m := gmcp.Message{Package: name}
+	if data.String() != "" {
+		encoded, err := vm.JSONData(data)
+		if err != nil {
+			log.Panicln("SendGMCP:", err)
+		}
+		m.Data = encoded
+	}
+	h.terminal.SendGMCP(m)
+}
@@ -1,63 +1,278 @@
 package game
 
 import (
+	"goMud/internal/account"
+	"goMud/internal/gmcp"
+	"goMud/internal/logging"
+	"goMud/internal/markup"
+	"goMud/internal/repl"
 	"goMud/internal/vm"
-	"log"
+	"log/slog"
+	"sync"
+	"time"
 )
 
+var logger = logging.Logger(logging.Game)
+
+// UnknownCommandMessage answers a line no command or action took.
+const UnknownCommandMessage = "What?"
+
 type Handler struct {
+	// mutex guards the connection, lineChannel to terminal, which a
+	// reconnect swaps on the VM goroutine.
+	mutex              sync.Mutex
 	lineChannel        chan string
 	lineSendingChannel chan string
-	vmHandlerObject    vm.Object
+	terminal           Terminal
+	vmHandlerObject    *vm.Object
 	context            HandlerContext
+	evalSession        *repl.Session
+	debugger           *vm.Debugger
+	profiler           *vm.Profiler
+	logger             *slog.Logger
+	login              *vm.Object
+	account            *account.Account
+	player             *vm.Object
+	// linkDead runs out the grace period of a player whose connection
+	// dropped, nil while connected.
+	linkDead *time.Timer
+	// handedTo is the handler of the same account this connection was handed
+	// over to on login.
+	handedTo *Handler
+	// gmcpSupports are the GMCP packages the client asked for.
+	gmcpSupports gmcp.Supports
+}
+
+// Terminal is what the game controls of a connection besides its lines.
+type Terminal interface {
+	// SetEcho turns the client's local echo on or off, off hides passwords.
+	SetEcho(enabled bool)
+	// Close hangs up, the line channel is closed once the connection is gone.
+	Close()
+	// Closed is closed once the connection is gone.
+	Closed() <-chan struct{}
+	// SetColour turns colour markup on or off, off strips it from lines.
+	SetColour(enabled bool)
+	// ColourMode is how colour markup is rendered for the client.
+	ColourMode() markup.Mode
+	// WindowSize is the client's window in characters.
+	WindowSize() (width int, height int)
+	ClientName() string
+	TerminalType() string
+	// TerminalFlags are the client's MTTS flags.
+	TerminalFlags() int
+	// SendGMCP sends a GMCP message if the client agreed to GMCP.
+	SendGMCP(m gmcp.Message)
+	// GMCP delivers the client's GMCP messages.
+	GMCP() <-chan gmcp.Message
 }
 
-func (h *Handler) handleLines() {
+// handleLines reads the lines and GMCP messages of a connection until it is
+// gone. While logging in it waits for each line to be handled, a login can
+// hand the connection over to another handler which then reads the
+// following lines. The wizard commands are only there for a logged in
+// wizard.
+func (h *Handler) handleLines(lines chan string, messages <-chan gmcp.Message) {
 	channel := vm.GetCommandChannel()
-	h.prepareContext()
-	log.Println("Handler started")
+	loggingIn := h.account == nil
+	wizard := !loggingIn && h.account.Wizard
 	for {
-		line := <-h.lineChannel
-		channel <- vm.NewMethodCallCommand(h.vmHandlerObject, "HandleLine", []vm.Value{vm.NewStringValue(line)}, &h.context)
+		var line string
+		var ok bool
+		select {
+		case m := <-messages:
+			if h.debugger != nil && h.debugger.Stopped() != nil {
+				// the VM would not take the command until debug continue
+				h.logger.Debug("GMCP message dropped while the VM is stopped", "package", m.Package)
+				continue
+			}
+			channel <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+				h.handleGMCP(machine, m)
+			})
+			continue
+		case line, ok = <-lines:
+		}
+		if !ok {
+			h.logger.Info("Connection lost")
+			if h.debugger != nil {
+				h.detachDebugger()
+			}
+			channel <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+				h.disconnected(machine, lines)
+			})
+			return
+		}
+		if loggingIn {
+			// the login object reads passwords
+			h.logger.Debug("Line received", "length", len(line))
+		} else {
+			h.logger.Debug("Line received", "line", line)
+		}
+		if wizard && h.isDebugCommand(line) {
+			h.handleDebugCommand(line)
+			continue
+		}
+		if h.debugger != nil && h.debugger.Stopped() != nil {
+			h.send("The VM is stopped in the debugger, use debug continue.")
+			continue
+		}
+		if wizard && h.isProfileCommand(line) {
+			channel <- h.newProfileCommand(line)
+			continue
+		}
+		if wizard && h.isEvalCommand(line) {
+			channel <- h.newEvalCommand(line)
+			continue
+		}
+		done := make(chan struct{})
+		channel <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+			defer close(done)
+			h.handleLine(machine, line)
+		})
+		if !loggingIn {
+			continue
+		}
+		<-done
+		if h.handedTo != nil {
+			go h.handedTo.handleLines(lines, messages)
+			return
+		}
+		loggingIn = h.login != nil
+		wizard = !loggingIn && h.account.Wizard
+	}
+}
+
+// send writes a line to the connection, dropping it when the connection is
+// gone so a link-dead player never blocks the VM.
+func (h *Handler) send(line string) {
+	h.mutex.Lock()
+	output, terminal := h.lineSendingChannel, h.terminal
+	h.mutex.Unlock()
+	select {
+	case output <- line:
+	case <-terminal.Closed():
+	}
+}
+
+// handleLine runs a line on the VM goroutine. Until the player logged in
+// the login object gets it, then the commands of the game and after them
+// the actions of the objects around the player, those of the player handler
+// last.
+func (h *Handler) handleLine(machine *vm.VirtualMachine, line string) {
+	if h.login != nil {
+		// the line may be a password, only the error is logged
+		if _, err := machine.Call(h.login, "HandleLine", []vm.Value{vm.NewStringValue(line)}, &h.context); err != nil {
+			h.logger.Error("Login failed", "error", err)
+			h.send("Something went wrong.")
+		}
+		return
+	}
+	if h.runCommand(machine, line) {
+		return
+	}
+	handled, err := machine.Command(h.player, h.vmHandlerObject, line, &h.context)
+	if err != nil {
+		h.logger.Error("Command failed", "line", line, "error", err)
+		h.send("Something went wrong.")
+		return
+	}
+	if !handled {
+		h.send(UnknownCommandMessage)
 	}
 }
 
 func (h *Handler) prepareContext() {
-	playerObject := h.newPlayerObject(h.lineSendingChannel)
-	h.context.setPlayer(playerObject)
-	h.context.setRoom(loadStartingLocation())
+	h.player = h.newPlayerObject()
+	h.context.setPlayer(h.player)
+	vm.GetCommandChannel() <- vm.NewFuncCommand(h.startLogin)
+}
+
+// moveTo puts the player into a room, the room in the context follows the
+// player's environment.
+func (h *Handler) moveTo(machine *vm.VirtualMachine, player *vm.Object, room *vm.Object) error {
+	from := player.Environment()
+	h.context.setRoom(room)
+	if err := machine.MoveObject(player, room, &h.context); err != nil {
+		return err
+	}
+	if from == room {
+		return nil
+	}
+	if from != nil {
+		machine.Tell(from, h.name()+" leaves.", &h.context, player)
+	}
+	machine.Tell(room, h.name()+" arrives.", &h.context, player)
+	h.showPresent(room)
+	return nil
 }
 
-func NewHandler(lineChannel chan string, lineSendingChannel chan string) *Handler {
+// NewHandler starts handling the lines of a session, sessionId ties its log
+// records to those of the connection.
+func NewHandler(sessionId int, lineChannel chan string, lineSendingChannel chan string, terminal Terminal) *Handler {
 	handler := &Handler{
-		lineChannel,
-		lineSendingChannel,
-		*vm.NewObject("player_handler"),
-		*newHandlerContext(),
+		lineChannel:        lineChannel,
+		lineSendingChannel: lineSendingChannel,
+		context:            *newHandlerContext(),
+		logger:             logger.With("session", sessionId),
+		terminal:           terminal,
+		gmcpSupports:       make(gmcp.Supports),
 	}
-	go handler.handleLines()
+	handler.prepareContext()
+	handler.logger.Info("Handler started")
+	go handler.handleLines(lineChannel, terminal.GMCP())
 	return handler
 }
 
-func (h *Handler) newPlayerObject(lineChannel chan string) *vm.Object {
+func (h *Handler) newPlayerObject() *vm.Object {
 	class := vm.NewEmptyClass("<player>")
 	fromClass := vm.NewObjectFromClass(*class)
 	class.RegisterInternalMethod("Send", 1, 0, func(values []vm.Value) []vm.Value {
-		lineChannel <- values[0].String()
+		h.send(values[0].String())
 		return []vm.Value{}
 	})
 	class.RegisterInternalMethod("String", 0, 1, func(values []vm.Value) []vm.Value {
 		return []vm.Value{vm.NewStringValue(fromClass.String())}
 	})
+	class.RegisterInternalMethod("SetEcho", 1, 0, func(values []vm.Value) []vm.Value {
+		h.terminal.SetEcho(vm.IsTruthy(values[0]))
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Width", 0, 1, func(values []vm.Value) []vm.Value {
+		width, _ := h.terminal.WindowSize()
+		return []vm.Value{vm.NewNumberValue(width)}
+	})
+	class.RegisterInternalMethod("Height", 0, 1, func(values []vm.Value) []vm.Value {
+		_, height := h.terminal.WindowSize()
+		return []vm.Value{vm.NewNumberValue(height)}
+	})
+	class.RegisterInternalMethod("ClientName", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(h.terminal.ClientName())}
+	})
+	class.RegisterInternalMethod("TerminalType", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(h.terminal.TerminalType())}
+	})
+	class.RegisterInternalMethod("TerminalFlags", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(h.terminal.TerminalFlags())}
+	})
+	class.RegisterInternalMethod("SendGMCP", 2, 0, func(values []vm.Value) []vm.Value {
+		h.sendGMCP(values[0].String(), values[1])
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("GMCPSupports", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(h.gmcpSupports.Version(values[0].String()))}
+	})
 	class.RegisterInternalMethod("MoveTo", 1, 0, func(values []vm.Value) []vm.Value {
 		room := values[0].(*vm.StringValue).Value
-		h.context.setRoom(getOrInitializeRoom(room))
+		if err := h.moveTo(vm.GetVirtualMachine(), fromClass, vm.LoadObject(room)); err != nil {
+			panic(err)
+		}
 		return []vm.Value{}
 
 	})
+	h.registerAccountMethods(class, fromClass)
 	return fromClass
 }
 
 func loadStartingLocation() *vm.Object {
-	return vm.NewObject("locations/room_a")
+	return vm.LoadObject("locations/room_a")
 }
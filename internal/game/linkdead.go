@@ -0,0 +1,93 @@
+package game
+
+import (
+	"goMud/internal/vm"
+	"strings"
+	"time"
+)
+
+// LinkDeadTimeout is how long a player whose connection dropped stays in the
+// world, waiting for the account to log in again.
+var LinkDeadTimeout = 10 * time.Minute
+
+// players holds the handler of every logged in account, link-dead or not, by
+// lower case account name. Only the VM goroutine touches it.
+var players = make(map[string]*Handler)
+
+func accountKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// disconnected runs on the VM goroutine once lines, the line channel of a
+// connection, is closed. A player who logged in goes link-dead, anything
+// else is cleaned up right away.
+func (h *Handler) disconnected(machine *vm.VirtualMachine, lines chan string) {
+	if lines != h.lineChannel {
+		// the handler was taken over by a newer connection
+		return
+	}
+	if h.account == nil || players[accountKey(h.account.Name)] != h {
+		h.quit(machine)
+		return
+	}
+	if LinkDeadTimeout <= 0 {
+		h.quit(machine)
+		return
+	}
+	h.logger.Info("Player is link-dead", "account", h.account.Name, "timeout", LinkDeadTimeout)
+	h.tellRoom(machine, h.name()+" has lost their link.")
+	var timer *time.Timer
+	timer = time.AfterFunc(LinkDeadTimeout, func() {
+		vm.GetCommandChannel() <- vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+			if h.linkDead == timer {
+				h.quit(machine)
+			}
+		})
+	})
+	h.linkDead = timer
+}
+
+// takeOver gives this handler the connection of from, which just logged in
+// to the same account. A connection the player still has is closed.
+func (h *Handler) takeOver(machine *vm.VirtualMachine, from *Handler) {
+	if h.linkDead != nil {
+		h.linkDead.Stop()
+		h.linkDead = nil
+		h.tellRoom(machine, h.name()+" has reconnected.")
+	} else {
+		h.send("Someone else logged in as you.")
+		h.terminal.Close()
+	}
+	h.mutex.Lock()
+	h.lineChannel = from.lineChannel
+	h.lineSendingChannel = from.lineSendingChannel
+	h.terminal = from.terminal
+	h.mutex.Unlock()
+	h.gmcpSupports = from.gmcpSupports
+	h.account = from.account
+	from.handedTo = h
+	h.logger.Info("Player reconnected", "account", h.account.Name)
+	h.send("You take over your character.")
+}
+
+// quit removes the player of a connection that is gone from the world.
+func (h *Handler) quit(machine *vm.VirtualMachine) {
+	h.linkDead = nil
+	if h.login != nil {
+		machine.Destruct(h.login)
+		h.login = nil
+	}
+	if h.account != nil && players[accountKey(h.account.Name)] == h {
+		delete(players, accountKey(h.account.Name))
+		h.logger.Info("Player left the world", "account", h.account.Name)
+		h.tellRoom(machine, h.name()+" fades out of the world.")
+	}
+	machine.Destruct(h.player)
+}
+
+// tellRoom tells the others in the player's room about the player.
+func (h *Handler) tellRoom(machine *vm.VirtualMachine, message string) {
+	if room := h.player.Environment(); room != nil {
+		machine.Tell(room, message, &h.context, h.player)
+	}
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"errors"
+	"goMud/internal/account"
+	"goMud/internal/vm"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// LoginObjectName is the mudlib class that talks a new connection through
+// logging in or creating a character. Every connection gets a clone of it,
+// so its variables hold the state of that connection's conversation.
+const LoginObjectName = "login"
+
+// Accounts is where player accounts are kept, the server points it into its
+// data directory.
+var Accounts = account.NewStore(filepath.Join(vm.DefaultDataPath, "accounts"))
+
+func (h *Handler) startLogin(machine *vm.VirtualMachine) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.logger.Error("Cannot start the login", "error", r)
+		}
+	}()
+	h.vmHandlerObject = machine.LoadObject("player_handler")
+	h.login = machine.CloneObject(LoginObjectName)
+	if _, err := machine.Call(h.login, "Start", nil, &h.context); err != nil {
+		h.logger.Error("Login failed to start", "error", err)
+	}
+}
+
+// finishLogin hands the connection over to the player handler in the
+// starting location.
+func (h *Handler) finishLogin(machine *vm.VirtualMachine, player *vm.Object) error {
+	if h.account == nil {
+		return errors.New("login without an account")
+	}
+	h.account.LastLogin = time.Now().UTC()
+	if err := Accounts.Save(h.account); err != nil {
+		h.logger.Warn("Cannot save account", "account", h.account.Name, "error", err)
+	}
+	if h.login != nil {
+		machine.Destruct(h.login)
+		h.login = nil
+	}
+	h.applyColour()
+	if previous := players[accountKey(h.account.Name)]; previous != nil {
+		previous.takeOver(machine, h)
+		machine.Destruct(player)
+		return nil
+	}
+	players[accountKey(h.account.Name)] = h
+	h.logger.Info("Player logged in", "account", h.account.Name)
+	return h.moveTo(machine, player, loadStartingLocation())
+}
+
+func boolValue(b bool) vm.Value {
+	if b {
+		return vm.NewNumberValue(1)
+	}
+	return vm.NewNumberValue(0)
+}
+
+// registerAccountMethods gives the player the methods the login object
+// needs. Passwords are hashed and checked in Go, the login object only
+// learns whether they matched.
+func (h *Handler) registerAccountMethods(class *vm.Class, player *vm.Object) {
+	class.RegisterInternalMethod("Name", 0, 1, func(values []vm.Value) []vm.Value {
+		if h.account == nil {
+			return []vm.Value{vm.NewStringValue("")}
+		}
+		return []vm.Value{vm.NewStringValue(h.account.Name)}
+	})
+	class.RegisterInternalMethod("ValidName", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{boolValue(account.ValidName(values[0].String()))}
+	})
+	class.RegisterInternalMethod("ValidPassword", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{boolValue(account.ValidPassword(values[0].String()))}
+	})
+	class.RegisterInternalMethod("AccountExists", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{boolValue(Accounts.Exists(values[0].String()))}
+	})
+	class.RegisterInternalMethod("CheckPassword", 2, 1, func(values []vm.Value) []vm.Value {
+		a, err := Accounts.Load(values[0].String())
+		if err != nil || !a.CheckPassword(values[1].String()) {
+			h.logger.Info("Failed login", "account", values[0].String())
+			return []vm.Value{boolValue(false)}
+		}
+		h.account = a
+		return []vm.Value{boolValue(true)}
+	})
+	// The password a new player chooses waits here for its confirmation,
+	// out of the login object whose variables end up in snapshots.
+	var pendingPassword string
+	class.RegisterInternalMethod("SetPendingPassword", 1, 0, func(values []vm.Value) []vm.Value {
+		pendingPassword = values[0].String()
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("ConfirmPassword", 1, 1, func(values []vm.Value) []vm.Value {
+		confirmed := pendingPassword != "" && values[0].String() == pendingPassword
+		pendingPassword = ""
+		return []vm.Value{boolValue(confirmed)}
+	})
+	class.RegisterInternalMethod("CreateAccount", 2, 1, func(values []vm.Value) []vm.Value {
+		a, err := Accounts.Create(values[0].String(), values[1].String())
+		if errors.Is(err, account.ErrExists) {
+			return []vm.Value{boolValue(false)}
+		}
+		if err != nil {
+			log.Panicln("CreateAccount:", err)
+		}
+		h.logger.Info("Account created", "account", a.Name)
+		h.account = a
+		return []vm.Value{boolValue(true)}
+	})
+	class.RegisterInternalMethod("SetAttribute", 2, 0, func(values []vm.Value) []vm.Value {
+		if h.account == nil {
+			log.Panicln("SetAttribute: not logged in")
+		}
+		h.account.Attributes[values[0].String()] = values[1].String()
+		if err := Accounts.Save(h.account); err != nil {
+			h.logger.Warn("Cannot save account", "account", h.account.Name, "error", err)
+		}
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Attribute", 1, 1, func(values []vm.Value) []vm.Value {
+		if h.account == nil {
+			return []vm.Value{vm.NewStringValue("")}
+		}
+		return []vm.Value{vm.NewStringValue(h.account.Attributes[values[0].String()])}
+	})
+	class.RegisterInternalMethod("Login", 0, 0, func(values []vm.Value) []vm.Value {
+		if err := h.finishLogin(vm.GetVirtualMachine(), player); err != nil {
+			log.Panicln("Login:", err)
+		}
+		return []vm.Value{}
+	})
+}
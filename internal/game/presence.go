@@ -0,0 +1,112 @@
+package game
+
+import (
+	"goMud/internal/account"
+	"goMud/internal/markup"
+	"goMud/internal/vm"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// commands are handled in Go before a line reaches the player handler, they
+// need the connected players the mudlib does not see.
+var commands = map[string]func(h *Handler, machine *vm.VirtualMachine, args string){
+	"color":  (*Handler).colour,
+	"colour": (*Handler).colour,
+	"say":    (*Handler).say,
+	"tell":   (*Handler).tell,
+	"who":    (*Handler).who,
+}
+
+func (h *Handler) runCommand(machine *vm.VirtualMachine, line string) bool {
+	verb, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+	command := commands[verb]
+	if command == nil {
+		return false
+	}
+	command(h, machine, strings.TrimSpace(args))
+	return true
+}
+
+// name is how other players see this one.
+func (h *Handler) name() string {
+	if h.account == nil {
+		return "Someone"
+	}
+	return h.account.Name
+}
+
+// handlerOf finds the handler of a player object, nil for other objects.
+func handlerOf(o *vm.Object) *Handler {
+	for _, h := range players {
+		if h.player == o {
+			return h
+		}
+	}
+	return nil
+}
+
+// showPresent tells the player who else is in the room.
+func (h *Handler) showPresent(room *vm.Object) {
+	for _, o := range room.AllInventory() {
+		if other := handlerOf(o); other != nil && other != h {
+			if other.linkDead != nil {
+				h.send(other.name() + " is here, staring blankly.")
+			} else {
+				h.send(other.name() + " is here.")
+			}
+		}
+	}
+}
+
+func (h *Handler) say(machine *vm.VirtualMachine, args string) {
+	if args == "" {
+		h.send("Say what?")
+		return
+	}
+	h.send("You say: " + markup.Escape(args))
+	h.tellRoom(machine, h.name()+" says: "+markup.Escape(args))
+}
+
+func (h *Handler) tell(machine *vm.VirtualMachine, args string) {
+	target, message, _ := strings.Cut(args, " ")
+	message = markup.Escape(strings.TrimSpace(message))
+	if target == "" || message == "" {
+		h.send("Tell whom what?")
+		return
+	}
+	other := players[accountKey(target)]
+	switch {
+	case other == nil:
+		h.send("There is no player called " + markup.Escape(account.DisplayName(target)) + ".")
+	case other == h:
+		h.send("You mutter to yourself.")
+	case other.linkDead != nil:
+		h.send(other.name() + " has lost their link and can not hear you.")
+	default:
+		other.send(h.name() + " tells you: " + message)
+		h.send("You tell " + other.name() + ": " + message)
+	}
+}
+
+func (h *Handler) who(machine *vm.VirtualMachine, args string) {
+	names := make([]string, 0, len(players))
+	for _, p := range players {
+		if p.linkDead != nil {
+			names = append(names, markup.Escape(p.name())+" (link-dead)")
+		} else {
+			names = append(names, markup.Escape(p.name()))
+		}
+	}
+	sort.Strings(names)
+	h.send("Players in the world:")
+	for _, n := range names {
+		h.send("  " + n)
+	}
+	if len(names) == 1 {
+		h.send("1 player.")
+	} else {
+		h.send(strconv.Itoa(len(names)) + " players.")
+	}
+}
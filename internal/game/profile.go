@@ -0,0 +1,117 @@
+package game
+
+import (
+	"errors"
+	"goMud/internal/vm"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const profileCommand = "profile"
+
+const defaultProfileTop = 10
+
+// WizardProfileEnabled lets wizards profile the VM with the profile
+// command. Profiles are saved on the server's disk.
+var WizardProfileEnabled = false
+
+const profileHelp = `profile start        start a new profile
+profile stop         stop profiling, the profile is kept for reports
+profile top [n]      show the n lines with the most time
+profile save <file>  write the profile for go tool pprof to the data directory`
+
+func (h *Handler) isProfileCommand(line string) bool {
+	return WizardProfileEnabled && (line == profileCommand || strings.HasPrefix(line, profileCommand+" "))
+}
+
+func (h *Handler) newProfileCommand(line string) vm.Command {
+	fields := strings.Fields(strings.TrimPrefix(line, profileCommand))
+	return vm.NewFuncCommand(func(machine *vm.VirtualMachine) {
+		if len(fields) == 0 {
+			h.send(profileHelp)
+			return
+		}
+		if err := h.runProfileCommand(machine, fields[0], fields[1:]); err != nil {
+			h.send("profile: " + err.Error())
+		}
+	})
+}
+
+// runProfileCommand runs on the VM goroutine, the only one touching an
+// attached profiler.
+func (h *Handler) runProfileCommand(machine *vm.VirtualMachine, command string, args []string) error {
+	switch command {
+	case "start":
+		machine.SetProfiler(vm.NewProfiler())
+		h.send("Profiling started.")
+		return nil
+	case "stop":
+		if machine.GetProfiler() == nil {
+			return errors.New("not profiling")
+		}
+		h.profiler = machine.GetProfiler()
+		machine.SetProfiler(nil)
+		h.send("Profiling stopped.")
+		return nil
+	}
+
+	p := machine.GetProfiler()
+	if p == nil {
+		p = h.profiler
+	}
+	if p == nil {
+		return errors.New("no profile, start with profile start")
+	}
+	switch command {
+	case "top":
+		n := defaultProfileTop
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return errors.New("invalid count " + args[0])
+			}
+		}
+		var b strings.Builder
+		if err := p.WriteTop(&b, n); err != nil {
+			return err
+		}
+		h.send(strings.TrimRight(b.String(), "\n"))
+	case "save":
+		if len(args) != 1 {
+			return errors.New("usage: profile save <file>")
+		}
+		path, err := profilePath(machine, args[0])
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := p.WritePprof(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		h.send("Profile written to " + path + ".")
+	default:
+		return errors.New("unknown command " + command)
+	}
+	return nil
+}
+
+// profilePath puts a profile into the data directory, wizards name the file
+// but can not write anywhere else.
+func profilePath(machine *vm.VirtualMachine, name string) (string, error) {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return "", errors.New("give a file name without a directory")
+	}
+	if err := os.MkdirAll(machine.DataPath(), 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Clean(filepath.Join(machine.DataPath(), name)), nil
+}
@@ -0,0 +1,105 @@
+// Package gmcp reads and writes the messages of the Generic MUD
+// Communication Protocol, https://tintin.mudhalla.net/protocols/gmcp/. A
+// message is a package name like "Char.Vitals", a space and a JSON value,
+// sent in a telnet subnegotiation of option 201.
+package gmcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Message is one GMCP message, Data is nil for a message without a value
+// like Core.Ping.
+type Message struct {
+	Package string
+	Data    json.RawMessage
+}
+
+var ErrInvalid = errors.New("invalid GMCP message")
+
+// Parse reads a message from the bytes of a subnegotiation after the option.
+func Parse(payload []byte) (Message, error) {
+	name, data, _ := bytes.Cut(payload, []byte{' '})
+	if len(name) == 0 {
+		return Message{}, ErrInvalid
+	}
+	m := Message{Package: string(name)}
+	if data = bytes.TrimSpace(data); len(data) > 0 {
+		if !json.Valid(data) {
+			return Message{}, ErrInvalid
+		}
+		m.Data = data
+	}
+	return m, nil
+}
+
+// Bytes is the message as it goes into a subnegotiation.
+func (m Message) Bytes() []byte {
+	if m.Data == nil {
+		return []byte(m.Package)
+	}
+	return append([]byte(m.Package+" "), m.Data...)
+}
+
+// Supports are the packages a client asked for with Core.Supports.Set, Add
+// and Remove, by lower case name with their version.
+type Supports map[string]int
+
+// Apply updates the supports from a Core.Supports message, it tells whether
+// m was one.
+func (s Supports) Apply(m Message) bool {
+	switch strings.ToLower(m.Package) {
+	case "core.supports.set":
+		clear(s)
+		s.add(m.Data)
+	case "core.supports.add":
+		s.add(m.Data)
+	case "core.supports.remove":
+		for name := range entries(m.Data) {
+			delete(s, name)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (s Supports) add(data json.RawMessage) {
+	for name, version := range entries(data) {
+		s[name] = version
+	}
+}
+
+// entries reads the list of "Package version" strings of a Core.Supports
+// message, a package without a version has version 1.
+func entries(data json.RawMessage) map[string]int {
+	var list []string
+	result := make(map[string]int)
+	if json.Unmarshal(data, &list) != nil {
+		return result
+	}
+	for _, entry := range list {
+		name, version, _ := strings.Cut(strings.TrimSpace(entry), " ")
+		n, err := strconv.Atoi(version)
+		if err != nil || n < 1 {
+			n = 1
+		}
+		result[strings.ToLower(name)] = n
+	}
+	return result
+}
+
+// Version tells which version of a package the client supports, 0 for none.
+// A package like Char.Vitals is supported when its module, Char, is.
+func (s Supports) Version(name string) int {
+	name = strings.ToLower(name)
+	if v, ok := s[name]; ok {
+		return v
+	}
+	module, _, _ := strings.Cut(name, ".")
+	return s[module]
+}
@@ -0,0 +1,279 @@
+package compiler
+
+import (
+	"fmt"
+	"goMud/internal/gmsl/lexer"
+	"strconv"
+	"strings"
+)
+
+type assemblerSection int
+
+const (
+	headerSection assemblerSection = iota
+	stringsSection
+	entriesSection
+)
+
+// Mnemonics printed by vm.Operation.String, accepted next to the compiler's own.
+var opCodeAliases = map[string]OpCode{
+	"EQ":    OpCmp,
+	"CPUSH": OpPushContext,
+	"RPOP":  OpPopToRegister,
+	"RPUSH": OpPushFromRegister,
+	"SPUSH": OpPushString,
+}
+
+type assembler struct {
+	input    string
+	pos      int
+	line     int
+	result   *Assembly
+	function *FunctionInfo
+	section  assemblerSection
+}
+
+func Assemble(input string) (*Assembly, error) {
+	a := &assembler{input: input, result: newAssembly()}
+	if err := a.run(); err != nil {
+		return nil, err
+	}
+	if err := a.result.Validate(); err != nil {
+		return nil, err
+	}
+	return a.result, nil
+}
+
+func (a *assembler) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", a.line, fmt.Sprintf(format, args...))
+}
+
+func (a *assembler) readLine() string {
+	a.line++
+	end := strings.IndexByte(a.input[a.pos:], '\n')
+	if end < 0 {
+		line := a.input[a.pos:]
+		a.pos = len(a.input)
+		return line
+	}
+	line := a.input[a.pos : a.pos+end]
+	a.pos += end + 1
+	return line
+}
+
+func (a *assembler) run() error {
+	for a.pos < len(a.input) {
+		lineStart := a.pos
+		line := strings.TrimSpace(a.readLine())
+		switch {
+		case line == "" || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "Function "):
+			if err := a.startFunction(line); err != nil {
+				return err
+			}
+			continue
+		case strings.HasPrefix(line, "Variable ") && a.function == nil:
+			if err := a.parseVariable(line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if a.function == nil {
+			return a.errorf("expected function header, got %q", line)
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(line, "Arguments:"):
+			a.function.arguments, err = a.parseTypes(strings.TrimPrefix(line, "Arguments:"))
+		case strings.HasPrefix(line, "Returns:"):
+			a.function.returns, err = a.parseTypes(strings.TrimPrefix(line, "Returns:"))
+		case line == "Strings:":
+			a.section = stringsSection
+		case line == "Entries:":
+			a.section = entriesSection
+		case a.section == stringsSection:
+			a.pos = lineStart
+			a.line--
+			err = a.parseString()
+		case a.section == entriesSection:
+			err = a.parseEntry(line)
+		default:
+			err = a.errorf("unexpected %q", line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	a.finishFunction()
+	return nil
+}
+
+func (a *assembler) parseVariable(line string) error {
+	name, typeName, found := strings.Cut(strings.TrimPrefix(line, "Variable "), ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return a.errorf("invalid variable %q", line)
+	}
+	t, ok := typeFromString(strings.TrimSpace(typeName))
+	if !ok {
+		return a.errorf("unknown type %q", strings.TrimSpace(typeName))
+	}
+	a.result.addVariable(name, t)
+	return nil
+}
+
+func (a *assembler) startFunction(line string) error {
+	a.finishFunction()
+	name := strings.TrimSuffix(strings.TrimPrefix(line, "Function "), ":")
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return a.errorf("invalid function name %q", name)
+	}
+	a.function = newFunctionInfo(name)
+	a.section = headerSection
+	return nil
+}
+
+func (a *assembler) finishFunction() {
+	if a.function == nil {
+		return
+	}
+	if a.function.nextLabel != nil {
+		a.function.addEntry(*NewNoOpEntry(nil, a.source("NOOP")))
+	}
+	a.result.addFunction(a.function)
+	a.function = nil
+}
+
+func (a *assembler) parseTypes(list string) ([]Type, error) {
+	types := make([]Type, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		t, ok := typeFromString(name)
+		if !ok {
+			return nil, a.errorf("unknown type %q", name)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// String pool entries are written unescaped, so the byte length in
+// string[n]: "..." decides where the value ends, even across newlines.
+func (a *assembler) parseString() error {
+	a.line++
+	rest := strings.TrimLeft(a.input[a.pos:], " \t")
+	a.pos = len(a.input) - len(rest)
+	if !strings.HasPrefix(rest, "string[") {
+		return a.errorf("expected string pool entry")
+	}
+	closing := strings.Index(rest, "]: \"")
+	if closing < 0 {
+		return a.errorf("malformed string pool entry")
+	}
+	length, err := strconv.Atoi(rest[len("string["):closing])
+	if err != nil || length < 0 {
+		return a.errorf("invalid string length %q", rest[len("string["):closing])
+	}
+	start := closing + len("]: \"")
+	if start+length >= len(rest) || rest[start+length] != '"' {
+		return a.errorf("string does not match declared length %d", length)
+	}
+	value := rest[start : start+length]
+	a.function.strings = append(a.function.strings, value)
+	a.pos += start + length + 1
+	a.line += strings.Count(value, "\n")
+
+	trailing := strings.TrimSpace(a.readLine())
+	a.line--
+	if trailing != "" {
+		return a.errorf("unexpected %q after string", trailing)
+	}
+	return nil
+}
+
+func (a *assembler) source(raw string) lexer.Token {
+	return lexer.NewToken(lexer.IdentifierToken, raw, lexer.Position{Line: a.line, Column: 1})
+}
+
+func (a *assembler) parseEntry(line string) error {
+	if comment := strings.IndexByte(line, ';'); comment >= 0 {
+		line = strings.TrimSpace(line[:comment])
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 1 && strings.HasSuffix(fields[0], ":") {
+		label := strings.TrimSuffix(fields[0], ":")
+		if a.function.nextLabel != nil {
+			a.function.addEntry(*NewNoOpEntry(nil, a.source("NOOP")))
+		}
+		a.function.setNextLabel(&label)
+		return nil
+	}
+
+	opCode, ok := parseOpCode(fields[0])
+	if !ok {
+		return a.errorf("unknown instruction %q", fields[0])
+	}
+	source := a.source(fields[0])
+	operands := fields[1:]
+
+	switch opCode {
+	case OpJump, OpJumpIfFalse:
+		if len(operands) != 1 {
+			return a.errorf("%s expects a label", fields[0])
+		}
+		if opCode == OpJump {
+			a.function.addEntry(*NewJumpEntry(nil, operands[0], source))
+		} else {
+			a.function.addEntry(*NewJumpIfFalseEntry(nil, operands[0], source))
+		}
+	case OpPushContext, OpPopToRegister, OpPushFromRegister, OpPushString, OpPushNumber, OpPushVariable, OpPopToVariable:
+		// RPOP and RPUSH print the register type before the index.
+		if len(operands) == 2 && (opCode == OpPopToRegister || opCode == OpPushFromRegister) {
+			operands = operands[1:]
+		}
+		if len(operands) != 1 {
+			return a.errorf("%s expects one argument", fields[0])
+		}
+		argument, err := strconv.Atoi(operands[0])
+		if err != nil {
+			return a.errorf("invalid argument %q", operands[0])
+		}
+		a.function.addEntry(AssemblyEntry{opCode: opCode, argument: &argument, source: source})
+	case OpCall:
+		// Without a count the call takes as many arguments as the method has.
+		if len(operands) > 1 {
+			return a.errorf("%s takes at most one argument", fields[0])
+		}
+		entry := AssemblyEntry{opCode: opCode, source: source}
+		if len(operands) == 1 {
+			argumentCount, err := strconv.Atoi(operands[0])
+			if err != nil {
+				return a.errorf("invalid argument %q", operands[0])
+			}
+			entry.argument = &argumentCount
+		}
+		a.function.addEntry(entry)
+	default:
+		if len(operands) != 0 {
+			return a.errorf("%s takes no arguments", fields[0])
+		}
+		a.function.addEntry(AssemblyEntry{opCode: opCode, source: source})
+	}
+	return nil
+}
+
+func parseOpCode(mnemonic string) (OpCode, bool) {
+	for opCode, name := range opCodeString {
+		if name == mnemonic {
+			return opCode, true
+		}
+	}
+	opCode, ok := opCodeAliases[mnemonic]
+	return opCode, ok
+}
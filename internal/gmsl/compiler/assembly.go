@@ -9,6 +9,8 @@ type Type int
 
 const (
 	StringType Type = iota
+	ObjectType
+	IntType
 )
 
 type IdentifierReference struct {
@@ -26,16 +28,39 @@ type FunctionInfo struct {
 	nextLabel         *string
 }
 
+// Variable is declared outside of functions, every object of the class has
+// its own value.
+type Variable struct {
+	name string
+	typ  Type
+}
+
+func (v Variable) GetName() string {
+	return v.name
+}
+
+func (v Variable) GetType() Type {
+	return v.typ
+}
+
 type Assembly struct {
+	variables []Variable
 	functions []FunctionInfo
 }
 
 func newAssembly() *Assembly {
-	return &Assembly{make([]FunctionInfo, 0)}
+	return &Assembly{make([]Variable, 0), make([]FunctionInfo, 0)}
 }
 
 func (a *Assembly) String() string {
 	var b bytes.Buffer
+	for _, v := range a.variables {
+		b.WriteString("Variable ")
+		b.WriteString(v.name)
+		b.WriteString(": ")
+		b.WriteString(v.typ.String())
+		b.WriteString("\n")
+	}
 	for _, f := range a.functions {
 		b.WriteString("Function ")
 		b.WriteString(f.name)
@@ -53,15 +78,53 @@ func (a *Assembly) GetFunctions() []FunctionInfo {
 	return a.functions
 }
 
+func (a *Assembly) addVariable(name string, t Type) {
+	a.variables = append(a.variables, Variable{name, t})
+}
+
+func (a *Assembly) variableIndex(name string) (int, bool) {
+	for n, v := range a.variables {
+		if v.name == name {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func (a *Assembly) GetVariables() []Variable {
+	return a.variables
+}
+
 func (t Type) String() string {
 	switch t {
 	case StringType:
 		return "string"
+	case ObjectType:
+		return "object"
+	case IntType:
+		return "int"
 	default:
 		return "unknown"
 	}
 }
 
+func (t Type) valid() bool {
+	return t == StringType || t == ObjectType || t == IntType
+}
+
+func typeFromString(s string) (Type, bool) {
+	switch s {
+	case "string":
+		return StringType, true
+	case "object":
+		return ObjectType, true
+	case "int":
+		return IntType, true
+	default:
+		return 0, false
+	}
+}
+
 func newFunctionInfo(name string) *FunctionInfo {
 	return &FunctionInfo{name,
 		make([]Type, 0),
@@ -90,6 +153,7 @@ func (f *FunctionInfo) String() string {
 			b.WriteString(", ")
 		}
 	}
+	b.WriteString("\n")
 	b.WriteString("Strings:\n")
 	for _, s := range f.strings {
 		b.WriteString("string[")
@@ -141,10 +205,33 @@ func (f *FunctionInfo) addIdentifier(value string, t Type) {
 	f.identifierNameMap[value] = IdentifierReference{len(f.identifierNameMap), t}
 }
 
+func (f *FunctionInfo) hasIdentifier(value string) bool {
+	_, ok := f.identifierNameMap[value]
+	return ok
+}
+
 func (f *FunctionInfo) getRegisterOf(value string) int {
 	return f.identifierNameMap[value].register
 }
 
+func (f *FunctionInfo) GetRegisterNames() []string {
+	names := make([]string, len(f.identifierNameMap))
+	for name, reference := range f.identifierNameMap {
+		names[reference.register] = name
+	}
+	return names
+}
+
+// GetRegisterTypes gives the declared type of every register, in the order
+// of GetRegisterNames.
+func (f *FunctionInfo) GetRegisterTypes() []Type {
+	types := make([]Type, len(f.identifierNameMap))
+	for _, reference := range f.identifierNameMap {
+		types[reference.register] = reference.typ
+	}
+	return types
+}
+
 func (f *FunctionInfo) GetName() string {
 	return f.name
 }
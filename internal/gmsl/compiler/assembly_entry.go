@@ -25,6 +25,8 @@ const (
 	OpPushString
 	OpPushNumber
 	OpNoOp
+	OpPushVariable
+	OpPopToVariable
 )
 
 var opCodeString = map[OpCode]string{
@@ -44,6 +46,8 @@ var opCodeString = map[OpCode]string{
 	OpPushString:       "PUSC",
 	OpPushNumber:       "PUSN",
 	OpNoOp:             "NOOP",
+	OpPushVariable:     "PUVA",
+	OpPopToVariable:    "POVA",
 }
 
 func (o OpCode) String() string {
@@ -92,6 +96,10 @@ func (a *AssemblyEntry) GetArgument() int {
 	return *a.argument
 }
 
+func (a *AssemblyEntry) HasArgument() bool {
+	return a.argument != nil
+}
+
 func (a *AssemblyEntry) GetTargetLabel() *string {
 	return a.labelArgument
 }
@@ -119,8 +127,10 @@ func NewOperationEntry(label *string, source lexer.Token) *AssemblyEntry {
 	}
 }
 
-func NewCallEntry(label *string, token lexer.Token) *AssemblyEntry {
-	return &AssemblyEntry{label: label, opCode: OpCall, argument: nil, source: token}
+// NewCallEntry calls a method with the given number of arguments, they are
+// on the stack below the method name and the object.
+func NewCallEntry(label *string, argumentCount int, token lexer.Token) *AssemblyEntry {
+	return &AssemblyEntry{label: label, opCode: OpCall, argument: &argumentCount, source: token}
 }
 
 func NewPushContextEntry(label *string, nameIdx int, source lexer.Token) *AssemblyEntry {
@@ -143,6 +153,14 @@ func NewPushFromRegisterEntry(label *string, register int, source lexer.Token) *
 	return &AssemblyEntry{label: label, opCode: OpPushFromRegister, argument: &register, source: source}
 }
 
+func NewPushVariableEntry(label *string, variable int, source lexer.Token) *AssemblyEntry {
+	return &AssemblyEntry{label: label, opCode: OpPushVariable, argument: &variable, source: source}
+}
+
+func NewPopToVariableEntry(label *string, variable int, source lexer.Token) *AssemblyEntry {
+	return &AssemblyEntry{label: label, opCode: OpPopToVariable, argument: &variable, source: source}
+}
+
 func NewPushStringEntry(label *string, stringIdx int, source lexer.Token) *AssemblyEntry {
 	return &AssemblyEntry{label: label, opCode: OpPushString, argument: &stringIdx, source: source}
 }
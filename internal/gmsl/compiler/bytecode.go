@@ -0,0 +1,441 @@
+package compiler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"goMud/internal/gmsl/lexer"
+	"io"
+	"strings"
+)
+
+// Layout of a .gmc file, all integers are varints from encoding/binary:
+//
+//	magic "GMC\x00", version
+//	variable count, then for each variable: name, type
+//	function count, then for each function:
+//	  name, argument types, return types, string pool, register names, entries
+//	register names: count, then for each register in order: name, type
+//	each entry:
+//	  flags (label, argument, label argument), opcode, optional fields,
+//	  source token type, raw value, line and column
+const (
+	SourceExtension   = ".gms"
+	BytecodeExtension = ".gmc"
+	BytecodeVersion   = 3
+)
+
+var bytecodeMagic = []byte{'G', 'M', 'C', 0}
+
+const (
+	entryHasLabel byte = 1 << iota
+	entryHasArgument
+	entryHasLabelArgument
+)
+
+const maxBytecodeLength = 1 << 20
+
+// RegisterCount is how many registers, arguments and locals, a function can
+// use. The VM gives every frame this many.
+const RegisterCount = 20
+
+func BytecodePath(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, SourceExtension) + BytecodeExtension
+}
+
+type bytecodeWriter struct {
+	w       *bufio.Writer
+	written int64
+	err     error
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (bw *bytecodeWriter) write(b []byte) {
+	if bw.err != nil {
+		return
+	}
+	n, err := bw.w.Write(b)
+	bw.written += int64(n)
+	bw.err = err
+}
+
+func (bw *bytecodeWriter) writeUint(v int) {
+	n := binary.PutUvarint(bw.scratch[:], uint64(v))
+	bw.write(bw.scratch[:n])
+}
+
+func (bw *bytecodeWriter) writeInt(v int) {
+	n := binary.PutVarint(bw.scratch[:], int64(v))
+	bw.write(bw.scratch[:n])
+}
+
+func (bw *bytecodeWriter) writeString(s string) {
+	bw.writeUint(len(s))
+	bw.write([]byte(s))
+}
+
+func (bw *bytecodeWriter) writeTypes(types []Type) {
+	bw.writeUint(len(types))
+	for _, t := range types {
+		bw.writeUint(int(t))
+	}
+}
+
+func (bw *bytecodeWriter) writeEntry(e *AssemblyEntry) {
+	var flags byte
+	if e.label != nil {
+		flags |= entryHasLabel
+	}
+	if e.argument != nil {
+		flags |= entryHasArgument
+	}
+	if e.labelArgument != nil {
+		flags |= entryHasLabelArgument
+	}
+	bw.write([]byte{flags})
+	bw.writeUint(int(e.opCode))
+	if e.label != nil {
+		bw.writeString(*e.label)
+	}
+	if e.argument != nil {
+		bw.writeInt(*e.argument)
+	}
+	if e.labelArgument != nil {
+		bw.writeString(*e.labelArgument)
+	}
+	position := e.source.GetPosition()
+	bw.writeUint(int(e.source.Typ))
+	bw.writeString(e.source.GetRawValue())
+	bw.writeUint(position.Line)
+	bw.writeUint(position.Column)
+}
+
+func (a *Assembly) WriteTo(w io.Writer) (int64, error) {
+	bw := &bytecodeWriter{w: bufio.NewWriter(w)}
+	bw.write(bytecodeMagic)
+	bw.writeUint(BytecodeVersion)
+	bw.writeUint(len(a.variables))
+	for _, v := range a.variables {
+		bw.writeString(v.name)
+		bw.writeUint(int(v.typ))
+	}
+	bw.writeUint(len(a.functions))
+	for _, f := range a.functions {
+		bw.writeString(f.name)
+		bw.writeTypes(f.arguments)
+		bw.writeTypes(f.returns)
+		bw.writeUint(len(f.strings))
+		for _, s := range f.strings {
+			bw.writeString(s)
+		}
+		bw.writeUint(len(f.identifierNameMap))
+		for _, name := range f.GetRegisterNames() {
+			bw.writeString(name)
+			bw.writeUint(int(f.identifierNameMap[name].typ))
+		}
+		bw.writeUint(len(f.entries))
+		for i := range f.entries {
+			bw.writeEntry(&f.entries[i])
+		}
+	}
+	if bw.err == nil {
+		bw.err = bw.w.Flush()
+	}
+	return bw.written, bw.err
+}
+
+type bytecodeReader struct {
+	r *bufio.Reader
+}
+
+func (br *bytecodeReader) readUint() (int, error) {
+	v, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return 0, err
+	}
+	if v > maxBytecodeLength {
+		return 0, fmt.Errorf("value %d out of range", v)
+	}
+	return int(v), nil
+}
+
+func (br *bytecodeReader) readInt() (int, error) {
+	v, err := binary.ReadVarint(br.r)
+	return int(v), err
+}
+
+func (br *bytecodeReader) readString() (string, error) {
+	n, err := br.readUint()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (br *bytecodeReader) readTypes() ([]Type, error) {
+	n, err := br.readUint()
+	if err != nil {
+		return nil, err
+	}
+	types := make([]Type, n)
+	for i := range types {
+		t, err := br.readUint()
+		if err != nil {
+			return nil, err
+		}
+		types[i] = Type(t)
+	}
+	return types, nil
+}
+
+func (br *bytecodeReader) readEntry() (AssemblyEntry, error) {
+	var e AssemblyEntry
+	flags, err := br.r.ReadByte()
+	if err != nil {
+		return e, err
+	}
+	opCode, err := br.readUint()
+	if err != nil {
+		return e, err
+	}
+	e.opCode = OpCode(opCode)
+	if flags&entryHasLabel != 0 {
+		label, err := br.readString()
+		if err != nil {
+			return e, err
+		}
+		e.label = &label
+	}
+	if flags&entryHasArgument != 0 {
+		argument, err := br.readInt()
+		if err != nil {
+			return e, err
+		}
+		e.argument = &argument
+	}
+	if flags&entryHasLabelArgument != 0 {
+		target, err := br.readString()
+		if err != nil {
+			return e, err
+		}
+		e.labelArgument = &target
+	}
+	typ, err := br.readUint()
+	if err != nil {
+		return e, err
+	}
+	raw, err := br.readString()
+	if err != nil {
+		return e, err
+	}
+	line, err := br.readUint()
+	if err != nil {
+		return e, err
+	}
+	column, err := br.readUint()
+	if err != nil {
+		return e, err
+	}
+	e.source = lexer.NewToken(lexer.TokenType(typ), raw, lexer.Position{Line: line, Column: column})
+	return e, nil
+}
+
+func (br *bytecodeReader) readFunction() (*FunctionInfo, error) {
+	name, err := br.readString()
+	if err != nil {
+		return nil, err
+	}
+	f := newFunctionInfo(name)
+	if f.arguments, err = br.readTypes(); err != nil {
+		return nil, err
+	}
+	if f.returns, err = br.readTypes(); err != nil {
+		return nil, err
+	}
+	stringCount, err := br.readUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < stringCount; i++ {
+		s, err := br.readString()
+		if err != nil {
+			return nil, err
+		}
+		f.strings = append(f.strings, s)
+	}
+	registerCount, err := br.readUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < registerCount; i++ {
+		name, err := br.readString()
+		if err != nil {
+			return nil, err
+		}
+		t, err := br.readUint()
+		if err != nil {
+			return nil, err
+		}
+		if f.hasIdentifier(name) {
+			return nil, fmt.Errorf("duplicate register %s", name)
+		}
+		f.addIdentifier(name, Type(t))
+	}
+	entryCount, err := br.readUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < entryCount; i++ {
+		e, err := br.readEntry()
+		if err != nil {
+			return nil, err
+		}
+		f.entries = append(f.entries, e)
+	}
+	return f, nil
+}
+
+func ReadAssembly(r io.Reader) (*Assembly, error) {
+	br := &bytecodeReader{bufio.NewReader(r)}
+	magic := make([]byte, len(bytecodeMagic))
+	if _, err := io.ReadFull(br.r, magic); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(magic) != string(bytecodeMagic) {
+		return nil, errors.New("not a GMSL bytecode file")
+	}
+	version, err := br.readUint()
+	if err != nil {
+		return nil, fmt.Errorf("reading version: %w", err)
+	}
+	if version != BytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d, expected %d", version, BytecodeVersion)
+	}
+	a := newAssembly()
+	variableCount, err := br.readUint()
+	if err != nil {
+		return nil, fmt.Errorf("reading variable count: %w", err)
+	}
+	for i := 0; i < variableCount; i++ {
+		name, err := br.readString()
+		if err != nil {
+			return nil, fmt.Errorf("reading variable %d: %w", i, err)
+		}
+		t, err := br.readUint()
+		if err != nil {
+			return nil, fmt.Errorf("reading variable %d: %w", i, err)
+		}
+		a.addVariable(name, Type(t))
+	}
+	functionCount, err := br.readUint()
+	if err != nil {
+		return nil, fmt.Errorf("reading function count: %w", err)
+	}
+	for i := 0; i < functionCount; i++ {
+		f, err := br.readFunction()
+		if err != nil {
+			return nil, fmt.Errorf("reading function %d: %w", i, err)
+		}
+		a.addFunction(f)
+	}
+	if _, err := br.r.ReadByte(); err != io.EOF {
+		return nil, errors.New("trailing data after last function")
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Assembly) Validate() error {
+	variables := make(map[string]bool)
+	for _, v := range a.variables {
+		if variables[v.name] {
+			return fmt.Errorf("duplicate variable %s", v.name)
+		}
+		if !v.typ.valid() {
+			return fmt.Errorf("variable %s: unknown type %d", v.name, v.typ)
+		}
+		variables[v.name] = true
+	}
+	names := make(map[string]bool)
+	for _, f := range a.functions {
+		if names[f.name] {
+			return fmt.Errorf("duplicate function %s", f.name)
+		}
+		names[f.name] = true
+		if err := f.validate(len(a.variables)); err != nil {
+			return fmt.Errorf("function %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (f *FunctionInfo) validate(variableCount int) error {
+	for _, t := range append(append(f.GetRegisterTypes(), f.arguments...), f.returns...) {
+		if !t.valid() {
+			return fmt.Errorf("unknown type %d", t)
+		}
+	}
+	if len(f.identifierNameMap) > RegisterCount {
+		return fmt.Errorf("%d registers, the VM has %d", len(f.identifierNameMap), RegisterCount)
+	}
+	labels := make(map[string]bool)
+	for _, e := range f.entries {
+		if e.label != nil {
+			if labels[*e.label] {
+				return fmt.Errorf("duplicate label %s", *e.label)
+			}
+			labels[*e.label] = true
+		}
+	}
+	for n, e := range f.entries {
+		if _, ok := opCodeString[e.opCode]; !ok {
+			return fmt.Errorf("entry %d: unknown opcode %d", n, e.opCode)
+		}
+		switch e.opCode {
+		case OpPushString, OpPushContext:
+			if e.argument == nil {
+				return fmt.Errorf("entry %d: %s requires an argument", n, e.opCode)
+			}
+			if *e.argument < 0 || *e.argument >= len(f.strings) {
+				return fmt.Errorf("entry %d: string index %d out of range", n, *e.argument)
+			}
+		case OpPopToRegister, OpPushFromRegister:
+			if e.argument == nil {
+				return fmt.Errorf("entry %d: %s requires an argument", n, e.opCode)
+			}
+			if *e.argument < 0 || *e.argument >= RegisterCount {
+				return fmt.Errorf("entry %d: register %d out of range", n, *e.argument)
+			}
+		case OpPushVariable, OpPopToVariable:
+			if e.argument == nil {
+				return fmt.Errorf("entry %d: %s requires an argument", n, e.opCode)
+			}
+			if *e.argument < 0 || *e.argument >= variableCount {
+				return fmt.Errorf("entry %d: variable %d out of range", n, *e.argument)
+			}
+		case OpCall:
+			if e.argument != nil && *e.argument < 0 {
+				return fmt.Errorf("entry %d: negative argument count %d", n, *e.argument)
+			}
+		case OpPushNumber:
+			if e.argument == nil {
+				return fmt.Errorf("entry %d: %s requires an argument", n, e.opCode)
+			}
+		case OpJump, OpJumpIfFalse:
+			if e.labelArgument == nil {
+				return fmt.Errorf("entry %d: %s requires a target label", n, e.opCode)
+			}
+			if !labels[*e.labelArgument] {
+				return fmt.Errorf("entry %d: unknown label %s", n, *e.labelArgument)
+			}
+		}
+	}
+	return nil
+}
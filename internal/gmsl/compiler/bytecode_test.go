@@ -0,0 +1,111 @@
+package compiler_test
+
+import (
+	"bytes"
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/compiler"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const bytecodeSource = `package main
+
+var count int
+
+func Add(a int b int) int {
+    sum := a + b
+    count = count + sum
+    return sum
+}
+
+func Describe(name string) string {
+    label := "small"
+    if count == 3 {
+        label = "three"
+    }
+    return name + " " + label
+}
+`
+
+func compile(t *testing.T) *compiler.Assembly {
+	t.Helper()
+	result := gmsl.Compile("bytecode", bytecodeSource)
+	if result.HasErrors() {
+		t.Fatal(result.Diagnostics[0])
+	}
+	return result.Assembly
+}
+
+func encode(t *testing.T, a *compiler.Assembly) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := a.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	a := compile(t)
+	read, err := compiler.ReadAssembly(bytes.NewReader(encode(t, a)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.String() != a.String() {
+		t.Fatalf("listing changed:\n%s\nwant:\n%s", read.String(), a.String())
+	}
+	for i, f := range read.GetFunctions() {
+		want := a.GetFunctions()[i]
+		if !slices.Equal(f.GetRegisterNames(), want.GetRegisterNames()) {
+			t.Errorf("%s registers = %v, want %v", f.GetName(), f.GetRegisterNames(), want.GetRegisterNames())
+		}
+		if !slices.Equal(f.GetRegisterTypes(), want.GetRegisterTypes()) {
+			t.Errorf("%s register types = %v, want %v", f.GetName(), f.GetRegisterTypes(), want.GetRegisterTypes())
+		}
+	}
+}
+
+func TestBytecodeRejectsTruncated(t *testing.T) {
+	data := encode(t, compile(t))
+	for _, n := range []int{0, 3, 5, len(data) / 2, len(data) - 1} {
+		if _, err := compiler.ReadAssembly(bytes.NewReader(data[:n])); err == nil {
+			t.Errorf("%d of %d bytes read without an error", n, len(data))
+		}
+	}
+}
+
+func TestBytecodeRejectsOtherVersion(t *testing.T) {
+	data := encode(t, compile(t))
+	// the version is a one byte varint after the four byte magic
+	data[4] = compiler.BytecodeVersion + 1
+	_, err := compiler.ReadAssembly(bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("error = %v, want a version error", err)
+	}
+}
+
+func TestBytecodeRejectsUnknownType(t *testing.T) {
+	data := encode(t, compile(t))
+	// magic, version, variable count, then "count" and its type
+	typeAt := 4 + 1 + 1 + 1 + len("count")
+	data[typeAt] = 9
+	_, err := compiler.ReadAssembly(bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "unknown type 9") {
+		t.Fatalf("error = %v, want an unknown type error", err)
+	}
+}
+
+func TestAssembleRejectsOutOfRange(t *testing.T) {
+	listing := func(entry string) string {
+		return "Variable v: int\nFunction F:\nArguments: \nReturns: \nStrings:\nstring[1]: \"x\"\nEntries:\nPUSN 1\n" + entry + "\nRET\n"
+	}
+	if _, err := compiler.Assemble(listing("POPR 19")); err != nil {
+		t.Fatalf("the last register was rejected: %v", err)
+	}
+	for _, entry := range []string{"POPR 20", "PURE -1", "PUSC 1", "POVA 1"} {
+		if _, err := compiler.Assemble(listing(entry)); err == nil {
+			t.Errorf("%q assembled without an error", entry)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package compiler
+
+import (
+	"fmt"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/logging"
+	"strings"
+)
+
+var logger = logging.Logger(logging.GMSL)
+
+type CompileError struct {
+	Token   lexer.Token
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	return e.Token.GetPosition().String() + ": " + e.Message
+}
+
+func compileError(token *lexer.Token, v ...any) {
+	err := &CompileError{Token: *token, Message: strings.TrimSuffix(fmt.Sprintln(v...), "\n")}
+	logger.Debug("Compile error", "position", err.Token.GetPosition(), "message", err.Message)
+	panic(err)
+}
@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"goMud/internal/gmsl/lexer"
 	"goMud/internal/gmsl/parser"
 	"log"
 	"strconv"
@@ -35,6 +36,12 @@ func (c *Compiler) processNode(node *parser.AstNode) {
 }
 
 func (c *Compiler) processClass(n *parser.Class) {
+	for _, v := range n.Variables {
+		if _, ok := c.result.variableIndex(v.GetVariableName()); ok {
+			compileError(v.GetToken(), "Duplicate variable", v.GetVariableName())
+		}
+		c.result.addVariable(v.GetVariableName(), typToType[v.GetType().Name])
+	}
 	for _, f := range n.Functions {
 		var a parser.AstNode = &f
 		c.processNode(&a)
@@ -60,6 +67,9 @@ func (c *Compiler) processFunctionDeclaration(n *parser.FunctionDeclaration) *Fu
 
 var typToType = map[string]Type{
 	"string": StringType,
+	"test":   ObjectType,
+	"object": ObjectType,
+	"int":    IntType,
 }
 
 func (c *Compiler) processArgumentDeclaration(argumentDeclaration *parser.ArgumentDeclaration, function *FunctionInfo) {
@@ -91,7 +101,7 @@ func (c *Compiler) processExpressionStatement(statement *parser.ExpressionStatem
 }
 
 func isContextName(name string) bool {
-	return name == "player" || name == "room" || name == "item"
+	return name == "player" || name == "room" || name == "item" || name == "driver" || name == "this"
 }
 
 func (c *Compiler) processExpression(expression *parser.Expression, f *FunctionInfo) []AssemblyEntry {
@@ -108,11 +118,16 @@ func (c *Compiler) processExpression(expression *parser.Expression, f *FunctionI
 		objectIdx := f.addString(objectName.Value)
 		if isContextName(objectName.Value) {
 			result = append(result, *NewPushContextEntry(nil, objectIdx, *objectName.GetToken()))
+		} else if f.hasIdentifier(objectName.Value) {
+			result = append(result, *NewPushFromRegisterEntry(nil, f.getRegisterOf(objectName.Value), *objectName.GetToken()))
+		} else if v, ok := c.result.variableIndex(objectName.Value); ok {
+			result = append(result, *NewPushVariableEntry(nil, v, *objectName.GetToken()))
 		} else {
 			result = append(result, *NewPushStringEntry(nil, objectIdx, *objectName.GetToken()))
 		}
 		n := (*expression).(*parser.MethodCallExpression).GetToken()
-		result = append(result, *NewCallEntry(nil, *n))
+		argumentCount := len((*expression).(*parser.MethodCallExpression).Arguments)
+		result = append(result, *NewCallEntry(nil, argumentCount, *n))
 	case *parser.BinaryExpression:
 		result = append(result, c.processExpression(&(*expression).(*parser.BinaryExpression).Left, f)...)
 		result = append(result, c.processExpression(&(*expression).(*parser.BinaryExpression).Right, f)...)
@@ -148,10 +163,13 @@ func (c *Compiler) processIfStatement(statement *parser.IfStatement, f *Function
 	f.setNextLabel(&jumpLabelName)
 
 	// Process the statements in the 'else' block, if it exists
-	if statement.ElseStatements != nil {
+	if len(statement.ElseStatements) > 0 {
 		for _, s := range statement.ElseStatements {
 			c.processStatement(&s, f)
 		}
+	} else {
+		// Without an else block both labels would land on the same entry
+		f.addEntry(*NewNoOpEntry(nil, *statement.GetToken()))
 	}
 
 	f.setNextLabel(&jumpToEndLabelName)
@@ -159,7 +177,21 @@ func (c *Compiler) processIfStatement(statement *parser.IfStatement, f *Function
 }
 
 func (c *Compiler) processIdentifierExpression(expression *parser.IdentifierExpression, f *FunctionInfo) AssemblyEntry {
-	return *NewPushFromRegisterEntry(nil, f.getRegisterOf(expression.Identifier.Value), *expression.GetToken())
+	name := expression.Identifier.Value
+	if isContextName(name) && !f.hasIdentifier(name) {
+		return *NewPushContextEntry(nil, f.addString(name), *expression.GetToken())
+	}
+	if v, ok := c.result.variableIndex(name); ok && !f.hasIdentifier(name) {
+		return *NewPushVariableEntry(nil, v, *expression.GetToken())
+	}
+	return *NewPushFromRegisterEntry(nil, c.registerOf(f, expression.Identifier.Value, expression.GetToken()), *expression.GetToken())
+}
+
+func (c *Compiler) registerOf(f *FunctionInfo, name string, token *lexer.Token) int {
+	if !f.hasIdentifier(name) {
+		compileError(token, "Undefined variable", name)
+	}
+	return f.getRegisterOf(name)
 }
 
 func (c *Compiler) processVariableDeclarationStatement(statement *parser.VariableDeclarationStatement, f *FunctionInfo) {
@@ -168,7 +200,11 @@ func (c *Compiler) processVariableDeclarationStatement(statement *parser.Variabl
 
 func (c *Compiler) processVariableAssignmentStatement(statement *parser.VariableAssignmentStatement, f *FunctionInfo) {
 	f.addEntries(c.processExpression(statement.GetExpression(), f))
-	f.addEntry(*NewPopToRegisterEntry(nil, f.getRegisterOf(statement.GetVariableName()), *statement.GetToken()))
+	if v, ok := c.result.variableIndex(statement.GetVariableName()); ok && !f.hasIdentifier(statement.GetVariableName()) {
+		f.addEntry(*NewPopToVariableEntry(nil, v, *statement.GetToken()))
+		return
+	}
+	f.addEntry(*NewPopToRegisterEntry(nil, c.registerOf(f, statement.GetVariableName(), statement.GetToken()), *statement.GetToken()))
 }
 
 func (c *Compiler) processVariableCreateAndAssignStatement(statement *parser.VariableCreateAndAssignStatement, f *FunctionInfo) {
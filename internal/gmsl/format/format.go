@@ -0,0 +1,261 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/gmsl/parser"
+	"log"
+	"strings"
+)
+
+// indentation is one level of indent, four spaces like the mudlib uses.
+const indentation = "    "
+
+type printer struct {
+	buf        bytes.Buffer
+	comments   []lexer.Comment
+	indent     int
+	lastLine   int
+	blockStart bool
+}
+
+func Source(file string, src []byte) ([]byte, error) {
+	result := gmsl.Parse(file, string(src))
+	if result.HasErrors() {
+		return nil, errors.New(result.Diagnostics[0].String())
+	}
+	return []byte(Class(result.Class, result.Comments)), nil
+}
+
+func Class(class *parser.Class, comments []lexer.Comment) string {
+	p := &printer{comments: comments}
+	p.class(class)
+	return p.buf.String()
+}
+
+func lineOf(token *lexer.Token) int {
+	return token.GetPosition().Line
+}
+
+func (p *printer) writeIndent() {
+	for i := 0; i < p.indent; i++ {
+		p.buf.WriteString(indentation)
+	}
+}
+
+func (p *printer) separate(line int) {
+	if !p.blockStart && p.lastLine > 0 && line > p.lastLine+1 {
+		p.buf.WriteString("\n")
+	}
+	p.blockStart = false
+}
+
+func (p *printer) flushComments(before int) {
+	for len(p.comments) > 0 && p.comments[0].Position.Line < before {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.separate(c.Position.Line)
+		p.writeIndent()
+		p.buf.WriteString(c.Text)
+		p.buf.WriteString("\n")
+		p.lastLine = c.Position.Line
+	}
+}
+
+// Comments left on the line just printed are kept at its end.
+func (p *printer) endLine(line int) {
+	for len(p.comments) > 0 && p.comments[0].Position.Line == line {
+		p.buf.WriteString(" ")
+		p.buf.WriteString(p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+	p.buf.WriteString("\n")
+	p.lastLine = line
+}
+
+func (p *printer) startItem(line int) {
+	p.flushComments(line)
+	p.separate(line)
+	p.writeIndent()
+}
+
+// Top level declarations are always separated by one blank line, comments
+// above a declaration stay attached to it.
+func (p *printer) topLevel(line int) {
+	p.separateTopLevel(line)
+	p.startItem(line)
+}
+
+func (p *printer) separateTopLevel(line int) {
+	p.buf.WriteString("\n")
+	p.lastLine = line - 1
+	if len(p.comments) > 0 && p.comments[0].Position.Line < line {
+		p.lastLine = p.comments[0].Position.Line - 1
+	}
+}
+
+func (p *printer) class(c *parser.Class) {
+	line := lineOf(c.GetToken())
+	p.startItem(line)
+	p.buf.WriteString("package ")
+	p.buf.WriteString(c.Name.Value)
+	p.endLine(line)
+
+	for _, i := range c.Imports {
+		line := lineOf(i.GetToken())
+		p.topLevel(line)
+		p.importDeclaration(i, line)
+	}
+	// Object variables form one group, blank lines inside it are kept
+	for n := range c.Variables {
+		if n == 0 {
+			p.separateTopLevel(lineOf(c.Variables[n].GetToken()))
+		}
+		p.statement(&c.Variables[n])
+	}
+	for n := range c.Functions {
+		f := &c.Functions[n]
+		p.topLevel(lineOf(f.GetToken()))
+		p.function(f)
+	}
+
+	if len(p.comments) > 0 {
+		p.buf.WriteString("\n")
+		p.lastLine = p.comments[0].Position.Line - 1
+		p.flushComments(p.comments[len(p.comments)-1].Position.Line + 1)
+	}
+}
+
+func (p *printer) importDeclaration(i parser.ImportDeclaration, line int) {
+	switch i := i.(type) {
+	case *parser.SingleImportDeclaration:
+		p.buf.WriteString("import \"")
+		p.buf.WriteString(i.Name.Value)
+		p.buf.WriteString("\"")
+		p.endLine(line)
+	case *parser.ImportDeclarationList:
+		p.buf.WriteString("import (")
+		p.endLine(line)
+		p.indent++
+		p.blockStart = true
+		for _, name := range i.Imports {
+			nameLine := lineOf(name.GetToken())
+			p.startItem(nameLine)
+			p.buf.WriteString("\"")
+			p.buf.WriteString(name.Value)
+			p.buf.WriteString("\"")
+			p.endLine(nameLine)
+		}
+		p.indent--
+		p.writeIndent()
+		p.buf.WriteString(")\n")
+	default:
+		log.Panicln("Unknown import type", i.String())
+	}
+}
+
+func (p *printer) function(f *parser.FunctionDeclaration) {
+	p.buf.WriteString("func ")
+	p.buf.WriteString(f.Name.Value)
+	p.buf.WriteString("(")
+	for n, a := range f.Arguments {
+		if n > 0 {
+			p.buf.WriteString(" ")
+		}
+		p.buf.WriteString(a.Name.Value)
+		p.buf.WriteString(" ")
+		p.buf.WriteString(a.Typ.Name)
+	}
+	p.buf.WriteString(")")
+	for _, r := range f.ReturnTypes {
+		p.buf.WriteString(" ")
+		p.buf.WriteString(r.Name)
+	}
+	p.buf.WriteString(" {")
+	p.endLine(lineOf(f.GetToken()))
+	p.block(f.Statements, f.GetEndToken())
+	p.endLine(p.lastLine)
+}
+
+func (p *printer) block(statements []parser.Statement, end *lexer.Token) {
+	p.indent++
+	p.blockStart = true
+	for _, s := range statements {
+		p.statement(s)
+	}
+	p.flushComments(lineOf(end))
+	p.indent--
+	p.blockStart = false
+	p.writeIndent()
+	p.buf.WriteString("}")
+	p.lastLine = lineOf(end)
+}
+
+func (p *printer) statement(s parser.Statement) {
+	line := lineOf(s.GetToken())
+	p.startItem(line)
+	switch s := s.(type) {
+	case *parser.ExpressionStatement:
+		p.buf.WriteString(Expression(s.ExpressionValue))
+	case *parser.VariableDeclarationStatement:
+		p.buf.WriteString("var ")
+		p.buf.WriteString(s.GetVariableName())
+		p.buf.WriteString(" ")
+		p.buf.WriteString(s.GetType().Name)
+	case *parser.VariableAssignmentStatement:
+		p.buf.WriteString(s.GetVariableName())
+		p.buf.WriteString(" = ")
+		p.buf.WriteString(Expression(*s.GetExpression()))
+	case *parser.VariableCreateAndAssignStatement:
+		p.buf.WriteString(s.GetVariableName())
+		p.buf.WriteString(" := ")
+		p.buf.WriteString(Expression(*s.GetExpression()))
+	case *parser.ReturnStatement:
+		p.buf.WriteString("return ")
+		p.buf.WriteString(Expression(*s.GetValue()))
+	case *parser.IfStatement:
+		p.ifStatement(s, line)
+		return
+	default:
+		log.Panicln("Unknown statement type", s.String())
+	}
+	p.endLine(line)
+}
+
+func (p *printer) ifStatement(s *parser.IfStatement, line int) {
+	p.buf.WriteString("if ")
+	p.buf.WriteString(Expression(s.Condition))
+	p.buf.WriteString(" {")
+	p.endLine(line)
+	p.block(s.Statements, s.GetEndToken())
+	if s.ElseStatements != nil {
+		p.buf.WriteString(" else {")
+		p.endLine(lineOf(s.GetEndToken()))
+		p.block(s.ElseStatements, s.GetElseEndToken())
+	}
+	p.endLine(p.lastLine)
+}
+
+func Expression(e parser.Expression) string {
+	switch e := e.(type) {
+	case *parser.BinaryExpression:
+		return Expression(e.Left) + " " + e.GetToken().GetRawValue() + " " + Expression(e.Right)
+	case *parser.MethodCallExpression:
+		arguments := make([]string, len(e.Arguments))
+		for n, a := range e.Arguments {
+			arguments[n] = Expression(a)
+		}
+		return e.ObjectName.Value + "." + e.MethodName.Value + "(" + strings.Join(arguments, " ") + ")"
+	case *parser.StringLiteralExpression:
+		return "\"" + e.GetToken().GetRawValue() + "\""
+	case *parser.NumericLiteralExpression:
+		return e.GetToken().GetRawValue()
+	case *parser.IdentifierExpression:
+		return e.Identifier.Value
+	default:
+		log.Panicln("Unknown expression type", e.String())
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package gmsl
+
+import (
+	"fmt"
+	"goMud/internal/gmsl/compiler"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/gmsl/parser"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const SeverityError = "error"
+
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	b.WriteString(d.File)
+	if d.Line > 0 {
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(d.Line))
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(d.Column))
+	}
+	b.WriteString(": ")
+	b.WriteString(d.Severity)
+	b.WriteString(": ")
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+type Result struct {
+	File        string
+	Source      string
+	Class       *parser.Class
+	Comments    []lexer.Comment
+	Assembly    *compiler.Assembly
+	Diagnostics []Diagnostic
+}
+
+func (r *Result) HasErrors() bool {
+	return len(r.Diagnostics) > 0
+}
+
+func ReadFile(path string) *Result {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return &Result{File: path, Diagnostics: []Diagnostic{{File: path, Severity: SeverityError, Message: err.Error()}}}
+	}
+	return &Result{File: path, Source: string(b)}
+}
+
+func ParseFile(path string) *Result {
+	r := ReadFile(path)
+	if !r.HasErrors() {
+		r.Parse()
+	}
+	return r
+}
+
+func CompileFile(path string) *Result {
+	r := ReadFile(path)
+	if !r.HasErrors() {
+		r.Compile()
+	}
+	return r
+}
+
+func Parse(file string, source string) *Result {
+	r := &Result{File: file, Source: source}
+	r.Parse()
+	return r
+}
+
+func Compile(file string, source string) *Result {
+	r := &Result{File: file, Source: source}
+	r.Compile()
+	return r
+}
+
+func (r *Result) Parse() {
+	defer r.recoverDiagnostic()
+	l := lexer.NewLexer(r.Source)
+	r.Class = parser.NewParser(l).Parse()
+	r.Comments = l.GetComments()
+}
+
+func (r *Result) Compile() {
+	if r.Class == nil {
+		r.Parse()
+		if r.HasErrors() {
+			return
+		}
+	}
+	defer r.recoverDiagnostic()
+	r.Assembly = compiler.NewCompiler(r.Class).Compile()
+}
+
+func (r *Result) Tokens() (tokens []lexer.Token) {
+	defer r.recoverDiagnostic()
+	tokens = make([]lexer.Token, 0)
+	l := lexer.NewLexer(r.Source)
+	for {
+		token := l.ReadNext()
+		tokens = append(tokens, *token)
+		switch token.Typ {
+		case lexer.InvalidToken:
+			r.addDiagnostic(token, token.GetRawValue())
+			return tokens
+		case lexer.EofToken:
+			return tokens
+		}
+	}
+}
+
+func (r *Result) addDiagnostic(token *lexer.Token, message string) {
+	position := token.GetPosition()
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{
+		File:     r.File,
+		Line:     position.Line,
+		Column:   position.Column,
+		Severity: SeverityError,
+		Message:  message,
+	})
+}
+
+func (r *Result) recoverDiagnostic() {
+	switch err := recover().(type) {
+	case nil:
+	case *parser.SyntaxError:
+		r.addDiagnostic(&err.Token, err.Message)
+	case *compiler.CompileError:
+		r.addDiagnostic(&err.Token, err.Message)
+	default:
+		message := strings.TrimSpace(fmt.Sprint(err))
+		r.Diagnostics = append(r.Diagnostics, Diagnostic{File: r.File, Severity: SeverityError, Message: message})
+	}
+}
@@ -1,19 +1,23 @@
 package lexer
 
 import (
-	"log"
+	"context"
+	"goMud/internal/logging"
+	"sort"
 	"strings"
 )
 
 type State func(*Lexer) State
 
 type Lexer struct {
-	input  string
-	start  int
-	pos    int
-	tokens chan Token
-	state  State
-	peeked []*Token
+	input       string
+	start       int
+	pos         int
+	tokens      chan Token
+	state       State
+	peeked      []*Token
+	lineOffsets []int
+	comments    []Comment
 }
 
 func (l *Lexer) run() {
@@ -26,13 +30,45 @@ func (l *Lexer) run() {
 
 func NewLexer(input string) *Lexer {
 	l := &Lexer{
-		input:  input,
-		tokens: make(chan Token, 2),
-		state:  defaultState,
+		input:       input,
+		tokens:      make(chan Token, 2),
+		state:       defaultState,
+		lineOffsets: []int{0},
+	}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			l.lineOffsets = append(l.lineOffsets, i+1)
+		}
 	}
 	return l
 }
 
+func (l *Lexer) positionOf(offset int) Position {
+	line := sort.Search(len(l.lineOffsets), func(i int) bool {
+		return l.lineOffsets[i] > offset
+	}) - 1
+	return Position{Line: line + 1, Column: offset - l.lineOffsets[line] + 1}
+}
+
+func (l *Lexer) skipComment() {
+	end := strings.IndexByte(l.input[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.input) - l.pos
+	}
+	text := strings.TrimRight(l.input[l.pos:l.pos+end], "\r")
+	l.comments = append(l.comments, Comment{Text: text, Position: l.positionOf(l.pos)})
+	l.pos += end
+	l.start = l.pos
+}
+
+func (l *Lexer) GetComments() []Comment {
+	return l.comments
+}
+
+func (l *Lexer) emit(typ TokenType, value string, offset int) {
+	l.tokens <- Token{typ, value, l.positionOf(offset)}
+}
+
 func (l *Lexer) nextToken() *Token {
 	for {
 		select {
@@ -44,16 +80,22 @@ func (l *Lexer) nextToken() *Token {
 	}
 }
 
+var logger = logging.Logger(logging.GMSL)
+
+func trace(message string, token *Token) {
+	logger.Log(context.Background(), logging.LevelTrace, message, "type", token.Typ, "value", token.rawValue, "position", token.position)
+}
+
 func (l *Lexer) ReadNext() *Token {
 	switch {
 	case len(l.peeked) > 0:
 		t := l.peeked[0]
 		l.peeked = l.peeked[1:]
-		log.Println("Peeked token", t)
+		trace("Peeked token", t)
 		return t
 	default:
 		token := l.nextToken()
-		log.Println("Read token", token)
+		trace("Read token", token)
 		return token
 	}
 }
@@ -139,13 +181,14 @@ func (l *Lexer) isOperator() bool {
 	return false
 }
 
-var types = [...]string{"int", "string"}
+var types = [...]string{"int", "string", "test", "object"}
 var validIdentifier = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_0123456789"
 
 func (l *Lexer) isType() bool {
 	for _, t := range types {
 		if strings.HasPrefix(l.input[l.pos:], t) {
-			return !strings.ContainsAny(l.input[l.pos+len(t):l.pos+len(t)+1], validIdentifier)
+			rest := l.input[l.pos+len(t):]
+			return rest == "" || !strings.ContainsAny(rest[:1], validIdentifier)
 		}
 	}
 	return false
@@ -168,7 +211,7 @@ func (l *Lexer) Peek() *Token {
 }
 
 func (l *Lexer) invalidToken() {
-	l.tokens <- Token{InvalidToken, "Invalid token near " + l.nextRunes(20)}
+	l.emit(InvalidToken, "Invalid token near "+l.nextRunes(20), l.pos)
 }
 
 func (l *Lexer) isNumeric() bool {
@@ -187,13 +230,18 @@ func defaultState(l *Lexer) State {
 whitespaces:
 	for {
 		if l.pos >= len(l.input) {
-			l.tokens <- Token{EofToken, ""}
+			l.emit(EofToken, "", l.pos)
 			return nil
 		}
 		switch l.input[l.pos] {
 		case ' ', '\t', '\n', '\r':
 			l.pos++
 			l.start++
+		case '/':
+			if !strings.HasPrefix(l.input[l.pos:], "//") {
+				break whitespaces
+			}
+			l.skipComment()
 		default:
 			break whitespaces
 		}
@@ -222,7 +270,7 @@ whitespaces:
 func numberState(lexer *Lexer) State {
 	for {
 		if lexer.pos >= len(lexer.input) || !lexer.isNumeric() {
-			lexer.tokens <- Token{NumericToken, lexer.input[lexer.start:lexer.pos]}
+			lexer.emit(NumericToken, lexer.input[lexer.start:lexer.pos], lexer.start)
 			lexer.start = lexer.pos
 			return defaultState
 		}
@@ -234,9 +282,9 @@ func numberState(lexer *Lexer) State {
 func keywordState(l *Lexer) State {
 	for k, v := range keywords {
 		if strings.HasPrefix(l.input[l.pos:], k+" ") {
+			l.emit(v, k, l.pos)
 			l.pos += len(k) + 1
 			l.start = l.pos
-			l.tokens <- Token{v, k}
 			return defaultState
 		}
 	}
@@ -247,19 +295,19 @@ func keywordState(l *Lexer) State {
 func identifierState(l *Lexer) State {
 	for {
 		if l.pos >= len(l.input) {
-			l.tokens <- Token{EofToken, ""}
+			l.emit(EofToken, "", l.pos)
 			return nil
 		}
 
 		if isParenthesis(rune(l.input[l.pos])) || isOperator(rune(l.input[l.pos])) {
-			l.tokens <- Token{IdentifierToken, l.input[l.start:l.pos]}
+			l.emit(IdentifierToken, l.input[l.start:l.pos], l.start)
 			l.start = l.pos
 			return defaultState
 		}
 
 		switch l.input[l.pos] {
 		case ' ', '\t', '\n', '\r':
-			l.tokens <- Token{IdentifierToken, l.input[l.start:l.pos]}
+			l.emit(IdentifierToken, l.input[l.start:l.pos], l.start)
 			l.start = l.pos
 			return defaultState
 		default:
@@ -271,9 +319,9 @@ func identifierState(l *Lexer) State {
 func parenthesisState(l *Lexer) State {
 	for k, v := range parenthesis {
 		if strings.HasPrefix(l.input[l.pos:], k) {
+			l.emit(v, k, l.pos)
 			l.pos += len(k)
 			l.start = l.pos
-			l.tokens <- Token{v, k}
 			return defaultState
 		}
 	}
@@ -282,47 +330,45 @@ func parenthesisState(l *Lexer) State {
 }
 
 func stringState(l *Lexer) State {
-	lastChar := rune(0)
 	for {
 		if l.pos >= len(l.input) {
-			l.tokens <- Token{EofToken, ""}
+			l.emit(EofToken, "", l.pos)
 			return nil
 		}
 		switch l.input[l.pos] {
 		case '"':
-			if lastChar != '\\' {
-				l.tokens <- Token{StringToken, l.input[l.start:l.pos]}
-				l.pos++
-				l.start = l.pos
-				return defaultState
-			}
+			l.emit(StringToken, l.input[l.start:l.pos], l.start-1)
 			l.pos++
+			l.start = l.pos
+			return defaultState
+		case '\\':
+			// skip the escaped character, it may be a quote
+			l.pos += 2
 		case '\r', '\n':
 			l.invalidToken()
 			return nil
 		default:
 			l.pos++
 		}
-		lastChar = rune(l.input[l.pos])
 	}
 }
 
 func operatorState(l *Lexer) State {
 	switch l.input[l.pos] {
 	case '=':
-		if l.input[l.pos+1] == '=' {
-			l.tokens <- Token{EqualToken, "=="}
+		if l.nextRunes(2) == "==" {
+			l.emit(EqualToken, "==", l.pos)
 			l.pos += 2
 			l.start = l.pos
 			return defaultState
 		}
-		l.tokens <- Token{AssignToken, "="}
+		l.emit(AssignToken, "=", l.pos)
 		l.pos++
 		l.start = l.pos
 		return defaultState
 	case ':':
-		if l.input[l.pos+1] == '=' {
-			l.tokens <- Token{CreateAndAssignToken, ":="}
+		if l.nextRunes(2) == ":=" {
+			l.emit(CreateAndAssignToken, ":=", l.pos)
 			l.pos += 2
 			l.start = l.pos
 			return defaultState
@@ -330,7 +376,7 @@ func operatorState(l *Lexer) State {
 		l.invalidToken()
 		return nil
 	case '.', '+', '-', '*', '/', '%':
-		l.tokens <- Token{operator[l.input[l.pos:l.pos+1]], l.input[l.pos : l.pos+1]}
+		l.emit(operator[l.input[l.pos:l.pos+1]], l.input[l.pos:l.pos+1], l.pos)
 		l.pos++
 		l.start = l.pos
 		return defaultState
@@ -342,9 +388,9 @@ func operatorState(l *Lexer) State {
 func typeState(l *Lexer) State {
 	for _, t := range types {
 		if strings.HasPrefix(l.input[l.pos:], t) {
+			l.emit(TypeToken, t, l.pos)
 			l.pos += len(t)
 			l.start = l.pos
-			l.tokens <- Token{TypeToken, t}
 			return defaultState
 		}
 	}
@@ -0,0 +1,45 @@
+package lexer
+
+import (
+	"slices"
+	"testing"
+)
+
+func stringValues(t *testing.T, input string) []string {
+	t.Helper()
+	l := NewLexer(input)
+	values := make([]string, 0)
+	for {
+		token := l.ReadNext()
+		switch token.Typ {
+		case EofToken:
+			return values
+		case InvalidToken:
+			t.Fatalf("%q: invalid token at %s", input, token.GetPosition())
+		case StringToken:
+			value, err := token.GetValueString()
+			if err != nil {
+				t.Fatalf("%q: %v", input, err)
+			}
+			values = append(values, value)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	for _, c := range []struct {
+		input string
+		want  []string
+	}{
+		{`"plain"`, []string{"plain"}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+		{`"\"" + "b"`, []string{`"`, "b"}},
+		{`"back\\" + "slash"`, []string{`back\`, "slash"}},
+		{`"\\\"" + "x"`, []string{`\"`, "x"}},
+		{`"tab\tend"`, []string{"tab\tend"}},
+	} {
+		if got := stringValues(t, c.input); !slices.Equal(got, c.want) {
+			t.Errorf("%q: strings %q, want %q", c.input, got, c.want)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package lexer
 
-import "bytes"
+import (
+	"bytes"
+	"strconv"
+)
 
 type TokenType int
 
@@ -66,9 +69,28 @@ func (t TokenType) String() string {
 	return tokenNames[t]
 }
 
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+type Comment struct {
+	Text     string
+	Position Position
+}
+
 type Token struct {
 	Typ      TokenType
 	rawValue string
+	position Position
+}
+
+func NewToken(typ TokenType, rawValue string, position Position) Token {
+	return Token{typ, rawValue, position}
 }
 
 func (t *Token) String() string {
@@ -79,6 +101,10 @@ func (t *Token) GetRawValue() string {
 	return t.rawValue
 }
 
+func (t *Token) GetPosition() Position {
+	return t.position
+}
+
 func (t *Token) GetValueString() (string, error) {
 	buffer := bytes.NewBufferString("")
 	reader := bytes.NewReader([]byte(t.rawValue))
@@ -3,7 +3,6 @@ package parser
 import (
 	"bytes"
 	"goMud/internal/gmsl/lexer"
-	"log"
 	"strconv"
 )
 
@@ -80,6 +79,7 @@ type ExpressionStatement struct {
 
 type FunctionDeclaration struct {
 	token       *lexer.Token
+	end         *lexer.Token
 	Name        Identifier
 	Arguments   []ArgumentDeclaration
 	ReturnTypes []Type
@@ -90,11 +90,14 @@ type Class struct {
 	token     *lexer.Token
 	Name      Identifier
 	Imports   []ImportDeclaration
+	Variables []VariableDeclarationStatement
 	Functions []FunctionDeclaration
 }
 
 type IfStatement struct {
 	token          *lexer.Token
+	end            *lexer.Token
+	elseEnd        *lexer.Token
 	Condition      Expression
 	Statements     []Statement
 	ElseStatements []Statement
@@ -270,6 +273,10 @@ func (c *Class) String() string {
 		buf.WriteString(" ")
 		buf.WriteString(i.String())
 	}
+	for _, v := range c.Variables {
+		buf.WriteString(" ")
+		buf.WriteString(v.String())
+	}
 	for _, f := range c.Functions {
 		buf.WriteString(" ")
 		buf.WriteString(f.String())
@@ -363,6 +370,10 @@ func (f *FunctionDeclaration) GetToken() *lexer.Token {
 	return f.token
 }
 
+func (f *FunctionDeclaration) GetEndToken() *lexer.Token {
+	return f.end
+}
+
 func (f *FunctionDeclaration) String() string {
 	var buf bytes.Buffer
 	buf.WriteString("(func ")
@@ -413,6 +424,14 @@ func (i *IfStatement) GetToken() *lexer.Token {
 	return i.token
 }
 
+func (i *IfStatement) GetEndToken() *lexer.Token {
+	return i.end
+}
+
+func (i *IfStatement) GetElseEndToken() *lexer.Token {
+	return i.elseEnd
+}
+
 func (i *IfStatement) String() string {
 	var buf bytes.Buffer
 	buf.WriteString("(if ")
@@ -452,7 +471,7 @@ func (n *NumericLiteralExpression) String() string {
 func (n *NumericLiteralExpression) GetValue() int {
 	result, err := strconv.Atoi(n.token.GetRawValue())
 	if err != nil {
-		log.Panicln("Error converting numeric literal to int", err)
+		syntaxError(n.token, "Error converting numeric literal to int", err)
 	}
 	return result
 }
@@ -1,9 +1,6 @@
 package parser
 
-import (
-	"goMud/internal/gmsl/lexer"
-	"log"
-)
+import "goMud/internal/gmsl/lexer"
 
 func newClass(name *Identifier, token *lexer.Token) *Class {
 	return &Class{token: token, Name: *name, Imports: make([]ImportDeclaration, 0)}
@@ -52,7 +49,7 @@ func newBinaryExpression(token *lexer.Token) *BinaryExpression {
 func newStringLiteralExpression(token *lexer.Token) *StringLiteralExpression {
 	valueString, err := token.GetValueString()
 	if err != nil {
-		log.Panicln("Error parsing string value", err)
+		syntaxError(token, "Error parsing string value", err)
 	}
 	return &StringLiteralExpression{token: token, Value: valueString}
 }
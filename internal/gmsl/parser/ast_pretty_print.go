@@ -11,6 +11,12 @@ func (c *Class) PrettyPrint(tabs int) string {
 		buffer.WriteString(i.PrettyPrint(tabs))
 		buffer.WriteString("\n")
 	}
+	for _, v := range c.Variables {
+		buffer.WriteString(v.PrettyPrint(tabs))
+	}
+	if len(c.Variables) > 0 {
+		buffer.WriteString("\n")
+	}
 	for _, f := range c.Functions {
 		buffer.WriteString(f.PrettyPrint(tabs))
 		buffer.WriteString("\n")
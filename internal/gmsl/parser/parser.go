@@ -1,11 +1,12 @@
 package parser
 
 import (
-	"fmt"
 	"goMud/internal/gmsl/lexer"
-	"log"
+	"goMud/internal/logging"
 )
 
+var logger = logging.Logger(logging.GMSL)
+
 type Parser struct {
 	lexer *lexer.Lexer
 }
@@ -15,16 +16,14 @@ func NewParser(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) Parse() *Class {
-	class := p.parseClass()
-	fmt.Println(class.PrettyPrint(0))
-	return class
+	return p.parseClass()
 }
 
 func (p *Parser) parseClass() *Class {
-	log.Println("Parsing class")
+	logger.Debug("Parsing class")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.PackageToken {
-		panic("Expected class")
+		syntaxError(token, "Expected PackageToken, got", token.String())
 	}
 
 	name := p.parseIdentifier()
@@ -35,6 +34,10 @@ func (p *Parser) parseClass() *Class {
 		case lexer.ImportToken:
 			imports := p.parseImportDeclarations()
 			class.Imports = append(class.Imports, imports...)
+		case lexer.VarToken:
+			// Variables outside of functions belong to the object
+			variable := p.parseVariableDeclarationStatement().(*VariableDeclarationStatement)
+			class.Variables = append(class.Variables, *variable)
 		case lexer.FuncToken:
 			functions := p.parseFunctionDeclarations()
 			class.Functions = append(class.Functions, functions...)
@@ -47,17 +50,17 @@ func (p *Parser) parseClass() *Class {
 }
 
 func (p *Parser) parseIdentifier() *Identifier {
-	log.Println("Parsing identifier")
+	logger.Debug("Parsing identifier")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.IdentifierToken {
-		log.Panicln("Expected identifier, got", token.String())
+		syntaxError(token, "Expected identifier, got", token.String())
 	}
 
 	return newIdentifier(token)
 }
 
 func (p *Parser) parseImportDeclarations() []ImportDeclaration {
-	log.Println("Parsing import declarations")
+	logger.Debug("Parsing import declarations")
 	token := p.lexer.Peek()
 	tokenDeclarations := make([]ImportDeclaration, 0)
 	if token.Typ == lexer.ImportToken {
@@ -69,14 +72,14 @@ func (p *Parser) parseImportDeclarations() []ImportDeclaration {
 }
 
 func (p *Parser) parseImportDeclaration() ImportDeclaration {
-	log.Println("Parsing import declaration")
+	logger.Debug("Parsing import declaration")
 	tokens := p.lexer.PeekSome(2)
 	if len(tokens) < 2 {
-		log.Panicln("Expected import declaration")
+		syntaxError(p.lexer.Peek(), "Expected import declaration")
 	}
 
 	switch tokens[1].Typ {
-	case lexer.IdentifierToken:
+	case lexer.StringToken:
 		return p.parseSingleImportDeclaration()
 	case lexer.OpenParenToken:
 		return p.parseImportDeclarationList()
@@ -87,7 +90,7 @@ func (p *Parser) parseImportDeclaration() ImportDeclaration {
 }
 
 func (p *Parser) parseSingleImportDeclaration() ImportDeclaration {
-	log.Println("Parsing single import declaration")
+	logger.Debug("Parsing single import declaration")
 	token := p.lexer.ReadNext()
 	name := p.parseStringValue()
 
@@ -95,7 +98,7 @@ func (p *Parser) parseSingleImportDeclaration() ImportDeclaration {
 }
 
 func (p *Parser) parseImportDeclarationList() ImportDeclaration {
-	log.Println("Parsing import declaration list")
+	logger.Debug("Parsing import declaration list")
 	token := p.lexer.ReadNext()
 	imports := make([]Identifier, 0)
 	skip := p.lexer.ReadNext()
@@ -115,10 +118,10 @@ func (p *Parser) parseImportDeclarationList() ImportDeclaration {
 }
 
 func (p *Parser) parseStringValue() *Identifier {
-	log.Println("Parsing string value")
+	logger.Debug("Parsing string value")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.StringToken {
-		log.Panicln("Expected string value, got", token.String())
+		syntaxError(token, "Expected string value, got", token.String())
 	}
 
 	return newIdentifier(token)
@@ -126,7 +129,7 @@ func (p *Parser) parseStringValue() *Identifier {
 }
 
 func (p *Parser) parseFunctionDeclarations() []FunctionDeclaration {
-	log.Println("Parsing function declarations")
+	logger.Debug("Parsing function declarations")
 	var functions []FunctionDeclaration
 	for {
 		token := p.lexer.Peek()
@@ -140,10 +143,10 @@ func (p *Parser) parseFunctionDeclarations() []FunctionDeclaration {
 }
 
 func (p *Parser) parseFunctionDeclaration() FunctionDeclaration {
-	log.Println("Parsing function declaration")
+	logger.Debug("Parsing function declaration")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.FuncToken {
-		log.Panicln("Expected FuncToken, got", token.String())
+		syntaxError(token, "Expected FuncToken, got", token.String())
 	}
 
 	name := p.parseIdentifier()
@@ -157,14 +160,15 @@ func (p *Parser) parseFunctionDeclaration() FunctionDeclaration {
 	default:
 		p.unexpectedToken(p.lexer.Peek())
 	}
-	statements := p.parseStatements()
+	statements, end := p.parseStatements()
 
 	declaration := newFunctionDeclaration(name, &arguments, returnTypes, &statements, token)
+	declaration.end = end
 	return *declaration
 }
 
 func (p *Parser) parseArgumentDeclarations() []ArgumentDeclaration {
-	log.Println("Parsing arguments")
+	logger.Debug("Parsing arguments")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.OpenParenToken {
 		p.unexpectedTokenExpected(lexer.OpenParenToken, token)
@@ -185,24 +189,24 @@ func (p *Parser) parseArgumentDeclarations() []ArgumentDeclaration {
 }
 
 func (p *Parser) parseArgumentDeclaration() ArgumentDeclaration {
-	log.Println("Parsing argument")
+	logger.Debug("Parsing argument")
 	name := p.parseIdentifier()
 	typ := p.parseType()
 	return *newArgumentDeclaration(name, typ, name.token)
 }
 
 func (p *Parser) parseType() *Type {
-	log.Println("Parsing type")
+	logger.Debug("Parsing type")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.TypeToken {
-		log.Panicln("Expected TypeToken, got", token.String())
+		syntaxError(token, "Expected TypeToken, got", token.String())
 	}
 
 	return newType(token)
 }
 
-func (p *Parser) parseStatements() []Statement {
-	log.Println("Parsing statements")
+func (p *Parser) parseStatements() ([]Statement, *lexer.Token) {
+	logger.Debug("Parsing statements")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.OpenBraceToken {
 		p.unexpectedTokenExpected(lexer.OpenBraceToken, token)
@@ -211,16 +215,14 @@ func (p *Parser) parseStatements() []Statement {
 	for {
 		token := p.lexer.Peek()
 		if token.Typ == lexer.CloseBraceToken {
-			p.lexer.ReadNext()
-			break
+			return statements, p.lexer.ReadNext()
 		}
 		statements = append(statements, p.parseStatement())
 	}
-	return statements
 }
 
 func (p *Parser) parseStatement() Statement {
-	log.Println("Parsing statement")
+	logger.Debug("Parsing statement")
 	peeked := p.lexer.PeekSome(2)
 
 	switch peeked[0].Typ {
@@ -249,14 +251,14 @@ func (p *Parser) parseStatement() Statement {
 }
 
 func (p *Parser) parseExpressionStatement() Statement {
-	log.Println("Parsing ExpressionValue statement")
+	logger.Debug("Parsing ExpressionValue statement")
 	token := p.lexer.Peek()
 	expression := p.parseExpression()
 	return newExpressionStatement(&expression, token)
 }
 
 func (p *Parser) parseExpression() Expression {
-	log.Println("Parsing ExpressionValue")
+	logger.Debug("Parsing ExpressionValue")
 	peeked := p.lexer.PeekSome(2)
 
 	tree := NewExpressionTree()
@@ -313,7 +315,7 @@ func (p *Parser) tryAddExpression(peeked []*lexer.Token, tree *ExpressionTree) (
 }
 
 func (p *Parser) parseMethodCallExpression() Expression {
-	log.Println("Parsing method call ExpressionValue")
+	logger.Debug("Parsing method call ExpressionValue")
 	token := p.lexer.Peek()
 	p.unexpectedTokenExpected(lexer.IdentifierToken, token)
 
@@ -321,7 +323,7 @@ func (p *Parser) parseMethodCallExpression() Expression {
 
 	token = p.lexer.ReadNext()
 	if token.Typ != lexer.MethodCallToken {
-		log.Panicln("Expected MethodCallToken, got", token.String())
+		syntaxError(token, "Expected MethodCallToken, got", token.String())
 	}
 	methodName := p.parseIdentifier()
 	arguments := p.parseArguments()
@@ -330,7 +332,7 @@ func (p *Parser) parseMethodCallExpression() Expression {
 }
 
 func (p *Parser) parseArguments() []Expression {
-	log.Println("Parsing arguments")
+	logger.Debug("Parsing arguments")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.OpenParenToken {
 		p.unexpectedTokenExpected(lexer.OpenParenToken, token)
@@ -351,42 +353,43 @@ func (p *Parser) parseArguments() []Expression {
 }
 
 func (p *Parser) parseStringLiteralExpression() Expression {
-	log.Println("Parsing string literal ExpressionValue")
+	logger.Debug("Parsing string literal ExpressionValue")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.StringToken {
-		log.Panicln("Expected StringToken, got", token.String())
+		syntaxError(token, "Expected StringToken, got", token.String())
 	}
 
 	return newStringLiteralExpression(token)
 }
 
 func (p *Parser) unexpectedToken(token *lexer.Token) {
-	log.Panicln("Unexpected token", token.String())
+	syntaxError(token, "Unexpected token", token.String())
 }
 
 func (p *Parser) unexpectedTokenExpected(expected lexer.TokenType, actual *lexer.Token) {
 	if actual.Typ != expected {
-		log.Panicln("Unexpected token", actual.String(), "expected", expected)
+		syntaxError(actual, "Unexpected token", actual.String(), "expected", expected)
 	}
 }
 
 func (p *Parser) parseIfStatement() Statement {
-	log.Println("Parsing if statement")
-	token := p.lexer.ReadNext()
-	if token.Typ != lexer.IfToken {
-		log.Panicln("Expected IfToken, got", token.String())
+	logger.Debug("Parsing if statement")
+	ifToken := p.lexer.ReadNext()
+	if ifToken.Typ != lexer.IfToken {
+		syntaxError(ifToken, "Expected IfToken, got", ifToken.String())
 	}
 
 	condition := p.parseExpression()
 
-	token = p.lexer.Peek()
+	token := p.lexer.Peek()
 	if token.Typ != lexer.OpenBraceToken {
 		p.unexpectedTokenExpected(lexer.OpenBraceToken, token)
 	}
 
-	statements := p.parseStatements()
+	statements, end := p.parseStatements()
 
 	var elseStatements []Statement
+	var elseEnd *lexer.Token
 	token = p.lexer.Peek()
 	if token.Typ == lexer.ElseToken {
 		p.lexer.ReadNext() // consume the 'else' token
@@ -396,14 +399,16 @@ func (p *Parser) parseIfStatement() Statement {
 			p.unexpectedTokenExpected(lexer.OpenBraceToken, token)
 		}
 
-		elseStatements = p.parseStatements()
+		elseStatements, elseEnd = p.parseStatements()
 	}
 
-	return newIfStatement(&condition, &statements, &elseStatements, token)
+	statement := newIfStatement(&condition, &statements, &elseStatements, ifToken)
+	statement.end, statement.elseEnd = end, elseEnd
+	return statement
 }
 
 func (p *Parser) parseIdentifierExpression() Expression {
-	log.Println("Parsing identifier ExpressionValue")
+	logger.Debug("Parsing identifier ExpressionValue")
 	token := p.lexer.Peek()
 	p.unexpectedTokenExpected(lexer.IdentifierToken, token)
 
@@ -411,10 +416,10 @@ func (p *Parser) parseIdentifierExpression() Expression {
 }
 
 func (p *Parser) parseVariableDeclarationStatement() Statement {
-	log.Println("Parsing variable declaration statement")
+	logger.Debug("Parsing variable declaration statement")
 	token := p.lexer.ReadNext()
 	if token.Typ != lexer.VarToken {
-		log.Panicln("Expected VarToken, got", token.String())
+		syntaxError(token, "Expected VarToken, got", token.String())
 	}
 
 	name := p.parseIdentifier()
@@ -424,7 +429,7 @@ func (p *Parser) parseVariableDeclarationStatement() Statement {
 }
 
 func (p *Parser) parseVariableAssignmentStatement() Statement {
-	log.Println("Parsing variable assignment statement")
+	logger.Debug("Parsing variable assignment statement")
 	token := p.lexer.Peek()
 	p.unexpectedTokenExpected(lexer.IdentifierToken, token)
 
@@ -436,7 +441,7 @@ func (p *Parser) parseVariableAssignmentStatement() Statement {
 }
 
 func (p *Parser) parseVariableCreateAndAssignStatement() Statement {
-	log.Println("Parsing variable create and assign statement")
+	logger.Debug("Parsing variable create and assign statement")
 	token := p.lexer.Peek()
 	if token.Typ != lexer.IdentifierToken {
 		p.unexpectedTokenExpected(lexer.IdentifierToken, token)
@@ -452,13 +457,13 @@ func (p *Parser) parseVariableCreateAndAssignStatement() Statement {
 func (p *Parser) expect(token lexer.TokenType, s string) *lexer.Token {
 	read := p.lexer.ReadNext()
 	if token != read.Typ {
-		log.Panicln("Expected", s, "got", token.String())
+		syntaxError(read, "Expected", s, "got", read.String())
 	}
 	return read
 }
 
 func (p *Parser) parseNumericLiteralExpression() Expression {
-	log.Println("Parsing numeric literal ExpressionValue")
+	logger.Debug("Parsing numeric literal ExpressionValue")
 	token := p.lexer.ReadNext()
 	p.unexpectedTokenExpected(lexer.NumericToken, token)
 
@@ -466,7 +471,7 @@ func (p *Parser) parseNumericLiteralExpression() Expression {
 }
 
 func (p *Parser) parseReturnStatement() Statement {
-	log.Println("Parsing return statement")
+	logger.Debug("Parsing return statement")
 	token := p.expect(lexer.ReturnToken, "ReturnToken")
 
 	expression := p.parseExpression()
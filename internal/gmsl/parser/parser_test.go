@@ -0,0 +1,48 @@
+package parser_test
+
+import (
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/parser"
+	"slices"
+	"testing"
+)
+
+func importNames(t *testing.T, source string) []string {
+	t.Helper()
+	result := gmsl.Parse("imports", source)
+	if result.HasErrors() {
+		t.Fatal(result.Diagnostics[0])
+	}
+	names := make([]string, 0)
+	for _, i := range result.Class.Imports {
+		switch i := i.(type) {
+		case *parser.SingleImportDeclaration:
+			names = append(names, i.Name.Value)
+		case *parser.ImportDeclarationList:
+			for _, n := range i.Imports {
+				names = append(names, n.Value)
+			}
+		}
+	}
+	return names
+}
+
+func TestImportPaths(t *testing.T) {
+	for _, c := range []struct {
+		source string
+		want   []string
+	}{
+		{"package main\n\nimport \"obj/coin\"\n", []string{"obj/coin"}},
+		{"package main\n\nimport (\n    \"obj/coin\"\n    \"obj/sword\"\n)\n", []string{"obj/coin", "obj/sword"}},
+	} {
+		if got := importNames(t, c.source); !slices.Equal(got, c.want) {
+			t.Errorf("%q: imports %q, want %q", c.source, got, c.want)
+		}
+	}
+}
+
+func TestImportNeedsQuotes(t *testing.T) {
+	if result := gmsl.Parse("imports", "package main\n\nimport coin\n"); !result.HasErrors() {
+		t.Fatal("an unquoted import parsed")
+	}
+}
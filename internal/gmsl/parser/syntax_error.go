@@ -0,0 +1,22 @@
+package parser
+
+import (
+	"fmt"
+	"goMud/internal/gmsl/lexer"
+	"strings"
+)
+
+type SyntaxError struct {
+	Token   lexer.Token
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Token.GetPosition().String() + ": " + e.Message
+}
+
+func syntaxError(token *lexer.Token, v ...any) {
+	err := &SyntaxError{Token: *token, Message: strings.TrimSuffix(fmt.Sprintln(v...), "\n")}
+	logger.Debug("Syntax error", "position", err.Token.GetPosition(), "message", err.Message)
+	panic(err)
+}
@@ -0,0 +1,124 @@
+package grammar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Case is a Polish grammatical case. Forms are always given in this order,
+// the one of Polish grammar books: mianownik, dopełniacz, celownik,
+// biernik, narzędnik, miejscownik, wołacz.
+type Case int
+
+const (
+	Nominative Case = iota
+	Genitive
+	Dative
+	Accusative
+	Instrumental
+	Locative
+	Vocative
+	CaseCount
+)
+
+// caseNames are the abbreviations mudlib code names cases by, as in the
+// PL_MIA, PL_DOP, ... constants of Arkadia.
+var caseNames = [CaseCount]string{"mia", "dop", "cel", "bie", "nar", "mie", "wol"}
+
+var fullCaseNames = [CaseCount]string{"mianownik", "dopełniacz", "celownik", "biernik", "narzędnik", "miejscownik", "wołacz"}
+
+// ParseCase reads a case name, the abbreviation or the full Polish name.
+func ParseCase(name string) (Case, bool) {
+	name = strings.ToLower(name)
+	for c := range caseNames {
+		if name == caseNames[c] || name == fullCaseNames[c] {
+			return Case(c), true
+		}
+	}
+	return 0, false
+}
+
+func (c Case) String() string {
+	if c < 0 || c >= CaseCount {
+		return fmt.Sprintf("Case(%d)", int(c))
+	}
+	return caseNames[c]
+}
+
+// Declension holds the forms of a noun or an adjective in every case and
+// both numbers, "miecz miecza mieczowi miecz mieczem mieczu mieczu".
+type Declension struct {
+	Singular [CaseCount]string
+	Plural   [CaseCount]string
+}
+
+// ParseDeclension reads the seven singular and seven plural forms, separated
+// by spaces.
+func ParseDeclension(singular string, plural string) (*Declension, error) {
+	d := &Declension{}
+	if err := parseForms(singular, &d.Singular); err != nil {
+		return nil, fmt.Errorf("singular: %w", err)
+	}
+	if err := parseForms(plural, &d.Plural); err != nil {
+		return nil, fmt.Errorf("plural: %w", err)
+	}
+	return d, nil
+}
+
+// Forms gives the forms back the way ParseDeclension reads them.
+func (d *Declension) Forms() (singular string, plural string) {
+	return strings.Join(d.Singular[:], " "), strings.Join(d.Plural[:], " ")
+}
+
+func parseForms(s string, forms *[CaseCount]string) error {
+	words := strings.Fields(strings.ToLower(s))
+	if len(words) != int(CaseCount) {
+		return fmt.Errorf("want %d forms, got %d", CaseCount, len(words))
+	}
+	copy(forms[:], words)
+	return nil
+}
+
+func (d *Declension) Form(c Case, plural bool) string {
+	if plural {
+		return d.Plural[c]
+	}
+	return d.Singular[c]
+}
+
+// Matches tells whether word is one of the forms, and whether it is a
+// plural one. Forms shared by both numbers count as singular.
+func (d *Declension) Matches(word string) (matches bool, plural bool) {
+	if slices.Contains(d.Singular[:], word) {
+		return true, false
+	}
+	return slices.Contains(d.Plural[:], word), true
+}
+
+// Counted gives the case and number a noun takes after a numeral: 1 miecz,
+// 2 miecze, 5 mieczy, 12 mieczy, 22 miecze.
+func Counted(n int) (Case, bool) {
+	if n == 1 {
+		return Nominative, false
+	}
+	if last, tens := n%10, n%100; last >= 2 && last <= 4 && (tens < 12 || tens > 14) {
+		return Nominative, true
+	}
+	return Genitive, true
+}
+
+// polishOrdinals are the stems of the ordinals, they take the ending of the
+// case and gender: drugi, drugiego, druga, drugą...
+var polishOrdinals = []string{"pierwsz", "drug", "trzec", "czwart", "piąt", "szóst", "siódm", "ósm", "dziewiąt", "dziesiąt"}
+
+var adjectiveEndings = []string{"y", "i", "a", "ą", "e", "ego", "emu", "ej", "ym", "im", "ymi", "imi", "ych", "ich"}
+
+func polishOrdinal(word string) int {
+	for n, stem := range polishOrdinals {
+		if ending, found := strings.CutPrefix(word, stem); found && slices.Contains(adjectiveEndings, ending) {
+			return n + 1
+		}
+	}
+	return 0
+}
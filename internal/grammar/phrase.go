@@ -0,0 +1,189 @@
+// Package grammar splits what players type about objects into noun phrases
+// the way classic muds read them: "take second red sword from chest", "drop
+// all coins", "give 3 coins to guard". Polish works too, "weź drugi
+// czerwony miecz z plecaka", and declension.go has what it takes to name
+// objects in the right case.
+package grammar
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// NounPhrase is one reference to objects, like "the second red sword".
+type NounPhrase struct {
+	// All is set by "all", alone or in front of a noun.
+	All bool
+	// Ordinal picks one of several matches, 1 for "first" or "1st", 0 when
+	// none was given.
+	Ordinal int
+	// Quantity asks for that many matches, 0 when none was given.
+	Quantity   int
+	Adjectives []string
+	// Noun is the last word, maybe a plural. It is empty for a bare "all".
+	Noun string
+}
+
+// Phrase is a command's arguments: a direct object and, after a
+// preposition, an indirect one.
+type Phrase struct {
+	Direct      NounPhrase
+	Preposition string
+	// Indirect is nil without a preposition.
+	Indirect *NounPhrase
+}
+
+// Prepositions are the words that start an indirect object, "out of" and
+// the Polish ones read as their English meaning.
+var Prepositions = []string{"from", "in", "into", "on", "onto", "at", "with", "to", "under", "behind"}
+
+var polishPrepositions = map[string]string{
+	"z": "from", "ze": "from", "do": "into", "w": "in", "we": "in", "na": "on",
+	"pod": "under", "za": "behind",
+}
+
+var allWords = map[string]bool{"all": true, "wszystko": true, "wszystkie": true}
+
+var articles = map[string]bool{"the": true, "a": true, "an": true}
+
+var ordinals = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5,
+	"sixth": 6, "seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10,
+}
+
+var numbers = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"dwa": 2, "dwie": 2, "trzy": 3, "cztery": 4, "pięć": 5,
+	"sześć": 6, "siedem": 7, "osiem": 8, "dziewięć": 9, "dziesięć": 10,
+}
+
+var ErrEmpty = errors.New("what do you mean")
+
+// Parse reads a phrase, words are matched in lower case.
+func Parse(text string) (*Phrase, error) {
+	words := strings.Fields(strings.ToLower(text))
+	for i := 1; i < len(words); i++ {
+		preposition := words[i]
+		rest := words[i+1:]
+		if preposition == "out" && len(rest) > 0 && rest[0] == "of" {
+			preposition, rest = "from", rest[1:]
+		} else if english, ok := polishPrepositions[preposition]; ok {
+			preposition = english
+		} else if !isPreposition(preposition) {
+			continue
+		}
+		direct, err := parseNounPhrase(words[:i])
+		if err != nil {
+			return nil, err
+		}
+		indirect, err := parseNounPhrase(rest)
+		if err != nil {
+			return nil, err
+		}
+		return &Phrase{Direct: *direct, Preposition: preposition, Indirect: indirect}, nil
+	}
+	direct, err := parseNounPhrase(words)
+	if err != nil {
+		return nil, err
+	}
+	return &Phrase{Direct: *direct}, nil
+}
+
+func isPreposition(word string) bool {
+	for _, p := range Prepositions {
+		if p == word {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNounPhrase(words []string) (*NounPhrase, error) {
+	np := &NounPhrase{}
+	words = skipArticles(words)
+	if len(words) > 0 && allWords[words[0]] {
+		np.All = true
+		words = words[1:]
+		if len(words) > 0 && words[0] == "of" {
+			words = words[1:]
+		}
+		words = skipArticles(words)
+	} else if len(words) > 1 {
+		if n := ordinal(words[0]); n > 0 {
+			np.Ordinal = n
+			words = words[1:]
+		} else if n := number(words[0]); n > 0 {
+			np.Quantity = n
+			words = words[1:]
+		}
+	}
+	if len(words) == 0 {
+		if np.All {
+			return np, nil
+		}
+		return nil, ErrEmpty
+	}
+	np.Adjectives = words[:len(words)-1]
+	np.Noun = words[len(words)-1]
+	return np, nil
+}
+
+func skipArticles(words []string) []string {
+	for len(words) > 0 && articles[words[0]] {
+		words = words[1:]
+	}
+	return words
+}
+
+// ordinal reads "second" or "2nd", 0 for anything else.
+func ordinal(word string) int {
+	if n, ok := ordinals[word]; ok {
+		return n
+	}
+	if n := polishOrdinal(word); n > 0 {
+		return n
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if digits, found := strings.CutSuffix(word, suffix); found {
+			if n, err := strconv.Atoi(digits); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// number reads "three" or "3", 0 for anything else.
+func number(word string) int {
+	if n, ok := numbers[word]; ok {
+		return n
+	}
+	if n, err := strconv.Atoi(word); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Singulars returns the words a noun could be the plural of, "swords" gives
+// "sword" and "boxes" gives "box" among guesses that match nothing.
+func Singulars(word string) []string {
+	result := make([]string, 0, 3)
+	if stem, found := strings.CutSuffix(word, "ies"); found && stem != "" {
+		result = append(result, stem+"y")
+	}
+	if stem, found := strings.CutSuffix(word, "es"); found && stem != "" {
+		result = append(result, stem)
+	}
+	if stem, found := strings.CutSuffix(word, "s"); found && stem != "" {
+		result = append(result, stem)
+	}
+	return result
+}
+
+// String gives the words of the phrase back without the ordinal or
+// quantity, for messages like "You see no red sword here."
+func (np NounPhrase) String() string {
+	return strings.Join(append(append([]string{}, np.Adjectives...), np.Noun), " ")
+}
@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	VM   = "vm"
+	GMSL = "gmsl"
+	Net  = "net"
+	Game = "game"
+)
+
+// LevelTrace sits below debug. At this level the VM logs every operation it
+// executes and the lexer every token it reads.
+const LevelTrace = slog.LevelDebug - 4
+
+var subsystems = []string{VM, GMSL, Net, Game}
+
+var levels = map[string]*slog.LevelVar{}
+
+var output atomic.Pointer[slog.Handler]
+
+func init() {
+	for _, s := range subsystems {
+		levels[s] = &slog.LevelVar{}
+	}
+	SetOutput(os.Stderr, false)
+}
+
+// SetOutput sends the records of every subsystem to w, as JSON lines or as
+// key=value text. Loggers created earlier follow the change.
+func SetOutput(w io.Writer, json bool) {
+	options := &slog.HandlerOptions{Level: LevelTrace, ReplaceAttr: replaceLevel}
+	var h slog.Handler
+	if json {
+		h = slog.NewJSONHandler(w, options)
+	} else {
+		h = slog.NewTextHandler(w, options)
+	}
+	output.Store(&h)
+}
+
+func replaceLevel(_ []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && a.Value.Any() == LevelTrace {
+		a.Value = slog.StringValue("TRACE")
+	}
+	return a
+}
+
+func SetLevel(subsystem string, level slog.Level) error {
+	l, ok := levels[subsystem]
+	if !ok {
+		return errors.New("unknown subsystem " + subsystem)
+	}
+	l.Set(level)
+	return nil
+}
+
+func SetAllLevels(level slog.Level) {
+	for _, l := range levels {
+		l.Set(level)
+	}
+}
+
+// ParseLevels reads a comma separated list of levels, a bare level applies to
+// every subsystem and subsystem=level to a single one, for example
+// "warn,vm=debug,net=trace".
+func ParseLevels(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		subsystem, name, found := strings.Cut(part, "=")
+		if !found {
+			name = subsystem
+		}
+		level, err := parseLevel(name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			SetAllLevels(level)
+		} else if err := SetLevel(subsystem, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseLevel(name string) (slog.Level, error) {
+	if strings.EqualFold(name, "trace") {
+		return LevelTrace, nil
+	}
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+// Logger returns the logger of a subsystem, its records carry the subsystem
+// as a field and are filtered by the subsystem's level.
+func Logger(subsystem string) *slog.Logger {
+	l, ok := levels[subsystem]
+	if !ok {
+		panic("unknown subsystem " + subsystem)
+	}
+	return slog.New(&handler{level: l, attrs: []slog.Attr{slog.String("subsystem", subsystem)}})
+}
+
+type handler struct {
+	level *slog.LevelVar
+	attrs []slog.Attr
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	record.AddAttrs(h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(a)
+		return true
+	})
+	return (*output.Load()).Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{level: h.level, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+// WithGroup flattens groups, nothing in the server nests its fields.
+func (h *handler) WithGroup(_ string) slog.Handler {
+	return h
+}
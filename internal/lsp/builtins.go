@@ -0,0 +1,119 @@
+package lsp
+
+type builtin struct {
+	name      string
+	signature string
+	doc       string
+}
+
+var contextNames = []builtin{
+	{"player", "player", "The player whose input is being handled."},
+	{"room", "room", "The room the player is currently in."},
+	{"item", "item", "The item the current command refers to."},
+	{"driver", "driver", "Driver functions, available everywhere including heartbeats."},
+	{"this", "this", "The object whose function is running."},
+}
+
+// Methods registered from Go on the objects behind context names, they have
+// no GMSL source to jump to.
+var internalMethods = map[string][]builtin{
+	"player": {
+		{"Send", "Send(message string)", "Sends a line of text to the player. Markup like {red}...{/}, {#ff8800}, {on_blue}, {bold} and {underline} is rendered for the client, {{ is a literal brace."},
+		{"MoveTo", "MoveTo(location string)", "Moves the player into the room loaded from the given mudlib path."},
+		{"String", "String() string", "Describes the player object."},
+		{"Name", "Name() string", "Returns the account name of the player, empty before login."},
+		{"ValidName", "ValidName(name string) int", "Tells whether name can be an account name."},
+		{"ValidPassword", "ValidPassword(password string) int", "Tells whether password is long enough to be used."},
+		{"AccountExists", "AccountExists(name string) int", "Tells whether there is an account with the name."},
+		{"CheckPassword", "CheckPassword(name string password string) int", "Checks the password of an account and on success makes it the player's account."},
+		{"SetPendingPassword", "SetPendingPassword(password string)", "Keeps the password a new player chose until it is confirmed."},
+		{"ConfirmPassword", "ConfirmPassword(password string) int", "Tells whether password matches the pending one and forgets it."},
+		{"CreateAccount", "CreateAccount(name string password string) int", "Creates an account for the player, 0 if the name is taken."},
+		{"SetAttribute", "SetAttribute(key string value string)", "Stores a character creation answer on the account."},
+		{"Attribute", "Attribute(key string) string", "Returns a value stored with SetAttribute."},
+		{"Login", "Login()", "Ends the login, the player handler gets the following lines."},
+		{"SetEcho", "SetEcho(enabled int)", "Turns the client's local echo on or off, off hides password input."},
+		{"Width", "Width() int", "Returns the width of the client's window in characters, 80 until the client reports it."},
+		{"Height", "Height() int", "Returns the height of the client's window in lines, 24 until the client reports it."},
+		{"ClientName", "ClientName() string", "Returns the name the client gave with TTYPE, like MUDLET, empty if it gave none."},
+		{"TerminalType", "TerminalType() string", "Returns the terminal the client emulates, like XTERM-256COLOR."},
+		{"TerminalFlags", "TerminalFlags() int", "Returns the client's MTTS flags, 0 if it sent none."},
+		{"SendGMCP", "SendGMCP(package string data object)", "Sends a GMCP message if the client agreed to GMCP. Data is a string, a number or an object from JSONObject, JSONArray or ParseJSON, an empty string sends no value. Messages from the client go to GMCP(name string data object) of the player handler."},
+		{"GMCPSupports", "GMCPSupports(package string) int", "Returns the version of a GMCP package the client asked for with Core.Supports, 0 if it did not."},
+	},
+	// Every object has Tell unless its class defines one.
+	"room": {
+		{"Tell", "Tell(message string exclude ...object)", "Sends the message to everything in the room with a Send method except the objects to exclude."},
+	},
+	"driver": {
+		{"SetHeartBeat", "SetHeartBeat(enabled int)", "Turns calls of HeartBeat() on this object every two seconds on or off."},
+		{"CallOut", "CallOut(method string delay int)", "Calls method on this object after delay seconds."},
+		{"CallOutWith", "CallOutWith(method string delay int arguments ...any)", "Calls method on this object with the arguments after delay seconds."},
+		{"RemoveCallOut", "RemoveCallOut(method string) int", "Cancels the next pending call of method and returns the seconds it had left, -1 if there was none."},
+		{"AddAction", "AddAction(verb string method string)", "Makes verb typed by a player near this object call method with the rest of the line, an action returning 0 lets the next one try."},
+		{"RemoveAction", "RemoveAction(verb string) int", "Removes the action this object added for verb, 0 if there was none."},
+		{"SetName", "SetName(name string)", "Makes name the noun players use for this object, it comes first in its short name."},
+		{"AddName", "AddName(name string)", "Adds another noun players can use for this object."},
+		{"AddAdjective", "AddAdjective(adjective string)", "Adds an adjective players can use for this object, it shows in its short name."},
+		{"ShortName", "ShortName(target object) string", "Returns the adjectives and name of the object, like \"red sword\"."},
+		{"SetDeclension", "SetDeclension(singular string plural string)", "Gives this object a Polish name, seven forms per number from mianownik to wołacz separated by spaces."},
+		{"AddAdjectiveDeclension", "AddAdjectiveDeclension(singular string plural string)", "Adds a Polish adjective to this object, its seven singular and seven plural forms."},
+		{"NameCase", "NameCase(target object case string) string", "Returns the name of the object in a case, \"dop\" or \"dopełniacz\" gives \"stalowego miecza\"."},
+		{"PluralNameCase", "PluralNameCase(target object case string) string", "Returns the plural name of the object in a case."},
+		{"CountedName", "CountedName(target object n int) string", "Returns n and the name in the form Polish wants after it, \"5 mieczy\"."},
+		{"Wrap", "Wrap(text string width int) string", "Breaks text into lines of at most width characters, colour markup does not count."},
+		{"JSONObject", "JSONObject() object", "Returns an empty JSON object for GMCP. It has Get(key), Set(key value), Count() and String()."},
+		{"JSONArray", "JSONArray() object", "Returns an empty JSON array for GMCP. It has Get(n), Append(value), Count() and String()."},
+		{"ParseJSON", "ParseJSON(text string) object", "Decodes JSON text into an object like those of JSONObject and JSONArray."},
+		{"Match", "Match(text string looker object) object", "Resolves a phrase like \"second red sword from bag\" against what looker carries and what is around it. The result has Error(), Count(), Get(n), Preposition() and Indirect()."},
+		{"LoadObject", "LoadObject(path string) object", "Returns the blueprint of the class at the mudlib path, loading it if needed."},
+		{"CloneObject", "CloneObject(path string) object", "Creates a new object of the class at the mudlib path, named path#id."},
+		{"FindObject", "FindObject(name string) object", "Returns the loaded blueprint or clone with the given name, 0 if there is none."},
+		{"Destruct", "Destruct(target object)", "Removes the object, references to it become false and calls to it fail."},
+		{"ObjectName", "ObjectName(target object) string", "Returns the name of the object, its path or path#id for clones."},
+		{"MoveObject", "MoveObject(target object destination object)", "Moves the object into destination, calling ObjectLeft on its old environment and ObjectEntered on destination."},
+		{"Environment", "Environment(target object) object", "Returns the object the target is in, 0 if it is nowhere."},
+		{"FirstInventory", "FirstInventory(container object) object", "Returns the first object inside container, 0 if it is empty."},
+		{"NextInventory", "NextInventory(target object) object", "Returns the object after target in its environment, 0 for the last one."},
+		{"SaveObject", "SaveObject(file string)", "Writes the variables of this object to the file in the data directory. JSON values are saved, other internal objects like Match results can not be."},
+		{"RestoreObject", "RestoreObject(file string) int", "Sets the variables of this object from the file in the data directory, returns 0 if there is no such file."},
+	},
+	// By convention the test argument of a TestXxx function is called t.
+	"t": {
+		{"Log", "Log(message string)", "Records a message shown with the test result."},
+		{"Error", "Error(message string)", "Marks the test failed and continues."},
+		{"Fatal", "Fatal(message string)", "Marks the test failed and stops it."},
+		{"Equal", "Equal(got any want any)", "Fails the test unless both values are equal."},
+		{"ExpectOutput", "ExpectOutput(line string)", "Fails the test unless the player was sent the line."},
+		{"Output", "Output() string", "Returns the lines sent to the player, one per line."},
+		{"ClearOutput", "ClearOutput()", "Forgets the lines sent to the player so far."},
+		{"Command", "Command(line string) int", "Runs the line through the actions around the player like typed input, 0 if none took it."},
+		{"Room", "Room() string", "Returns the mudlib path of the player's room."},
+		{"Advance", "Advance(seconds int)", "Moves the clock forward and runs the heartbeats and delayed calls that came due."},
+		{"SetRoom", "SetRoom(location string)", "Moves the player into the room without running the mudlib code of a move."},
+		{"SetWidth", "SetWidth(width int)", "Sets what player.Width() returns, 80 until it is called."},
+		{"SentGMCP", "SentGMCP(package string) int", "Returns how many GMCP messages of the package were sent to the player since the last ClearOutput."},
+		{"GMCPData", "GMCPData(package string) object", "Returns the value of the last GMCP message of the package sent to the player, 0 if there was none."},
+	},
+}
+
+var keywords = []string{"package", "import", "func", "if", "else", "var", "return"}
+
+func findContextName(name string) *builtin {
+	for i := range contextNames {
+		if contextNames[i].name == name {
+			return &contextNames[i]
+		}
+	}
+	return nil
+}
+
+func findInternalMethod(object string, name string) *builtin {
+	methods := internalMethods[object]
+	for i := range methods {
+		if methods[i].name == name {
+			return &methods[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/logging"
+	"io"
+	"sort"
+	"strings"
+)
+
+var logger = logging.Logger(logging.GMSL)
+
+type Server struct {
+	conn      *connection
+	root      string
+	documents map[string]*document
+	shutdown  bool
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var requestHandlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"shutdown":                (*Server).shutdownRequest,
+	"textDocument/definition": (*Server).definition,
+	"textDocument/hover":      (*Server).hover,
+	"textDocument/completion": (*Server).completion,
+}
+
+var notificationHandlers = map[string]handler{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didSave":   (*Server).didSave,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{conn: newConnection(in, out), documents: make(map[string]*document)}
+}
+
+// Serve handles messages until the client sends exit or closes the stream.
+// The returned bool tells whether shutdown was requested first.
+func (s *Server) Serve() (bool, error) {
+	for {
+		m, err := s.conn.read()
+		if err == io.EOF {
+			return s.shutdown, nil
+		}
+		if err != nil {
+			return s.shutdown, err
+		}
+		if m.Method == "exit" {
+			return s.shutdown, nil
+		}
+		if err := s.handle(m); err != nil {
+			return s.shutdown, err
+		}
+	}
+}
+
+func (s *Server) handle(m *message) error {
+	if m.Error != nil {
+		return s.conn.reply(nil, nil, m.Error)
+	}
+	if m.Id == nil {
+		if h, ok := notificationHandlers[m.Method]; ok {
+			if _, err := h(s, m.Params); err != nil {
+				logger.Warn("Notification failed", "method", m.Method, "error", err)
+			}
+		}
+		return nil
+	}
+
+	h, ok := requestHandlers[m.Method]
+	if !ok {
+		return s.conn.reply(m.Id, nil, &responseError{methodNotFound, "method not supported: " + m.Method})
+	}
+	result, err := h(s, m.Params)
+	if err != nil {
+		return s.conn.reply(m.Id, nil, &responseError{invalidParams, err.Error()})
+	}
+	return s.conn.reply(m.Id, result, nil)
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p initializeParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RootUri != "" {
+		s.root = findMudlibRoot(uriToPath(p.RootUri))
+	} else {
+		s.root = findMudlibRoot(p.RootPath)
+	}
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    syncFull,
+				"save":      map[string]any{"includeText": true},
+			},
+			"definitionProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+		},
+		"serverInfo": map[string]any{"name": "gmsl-lsp"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(_ json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) publishDiagnostics(d *document) {
+	err := s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{Uri: d.uri, Diagnostics: d.diagnostics()})
+	if err != nil {
+		logger.Warn("Publishing diagnostics failed", "uri", d.uri, "error", err)
+	}
+}
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var p didOpenParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	d := newDocument(p.TextDocument.Uri, p.TextDocument.Text)
+	s.documents[d.uri] = d
+	s.publishDiagnostics(d)
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var p didChangeParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	d, ok := s.documents[p.TextDocument.Uri]
+	if !ok || len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	d.update(p.ContentChanges[len(p.ContentChanges)-1].Text)
+	return nil, nil
+}
+
+func (s *Server) didSave(params json.RawMessage) (any, error) {
+	var p didSaveParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	d, ok := s.documents[p.TextDocument.Uri]
+	if !ok {
+		return nil, nil
+	}
+	if p.Text != nil {
+		d.update(*p.Text)
+	}
+	s.publishDiagnostics(d)
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var p didCloseParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.documents, p.TextDocument.Uri)
+	return nil, s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{Uri: p.TextDocument.Uri, Diagnostics: []diagnostic{}})
+}
+
+func (s *Server) documentAt(params json.RawMessage) (*document, position, error) {
+	var p textDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, position{}, err
+	}
+	d, ok := s.documents[p.TextDocument.Uri]
+	if !ok {
+		return nil, p.Position, errors.New("document not open: " + p.TextDocument.Uri)
+	}
+	return d, p.Position, nil
+}
+
+func isMethodName(d *document, i int) bool {
+	previous := d.token(i - 1)
+	return previous != nil && previous.Typ == lexer.MethodCallToken && d.token(i-2) != nil
+}
+
+func isObjectName(d *document, i int) bool {
+	next := d.token(i + 1)
+	return next != nil && next.Typ == lexer.MethodCallToken
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	d, pos, err := s.documentAt(params)
+	if err != nil {
+		return nil, err
+	}
+	i := d.tokenAt(pos)
+	token := d.token(i)
+	if token == nil {
+		return nil, nil
+	}
+
+	switch {
+	case token.Typ == lexer.StringToken:
+		for _, imported := range d.imports() {
+			if imported == token.GetRawValue() {
+				return s.classLocation(imported), nil
+			}
+		}
+	case token.Typ != lexer.IdentifierToken:
+	case isMethodName(d, i):
+		locations := make([]location, 0)
+		for _, f := range s.methodCandidates(d, d.token(i-2).GetRawValue(), token.GetRawValue()) {
+			locations = append(locations, f.location())
+		}
+		return locations, nil
+	case isObjectName(d, i):
+		if imported, ok := d.imports()[token.GetRawValue()]; ok {
+			return s.classLocation(imported), nil
+		}
+	default:
+		for _, v := range d.variablesAt(pos.Line + 1) {
+			if v.name == token.GetRawValue() {
+				return location{Uri: d.uri, Range: tokenRange(v.token)}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) classLocation(name string) location {
+	return location{Uri: pathToUri(s.classPath(name))}
+}
+
+func codeBlock(lines ...string) string {
+	return "```gmsl\n" + strings.Join(lines, "\n") + "\n```"
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	d, pos, err := s.documentAt(params)
+	if err != nil {
+		return nil, err
+	}
+	i := d.tokenAt(pos)
+	token := d.token(i)
+	if token == nil || token.Typ != lexer.IdentifierToken {
+		return nil, nil
+	}
+	name := token.GetRawValue()
+	r := tokenRange(token)
+
+	var contents string
+	switch {
+	case isMethodName(d, i):
+		object := d.token(i - 2).GetRawValue()
+		if b := findInternalMethod(object, name); b != nil {
+			contents = codeBlock(object+"."+b.signature) + "\n\n" + b.doc
+			break
+		}
+		signatures := make([]string, 0)
+		for _, f := range s.methodCandidates(d, object, name) {
+			signatures = append(signatures, f.signature())
+		}
+		if len(signatures) > 0 {
+			contents = codeBlock(signatures...)
+		}
+	case isObjectName(d, i) && findContextName(name) != nil:
+		contents = codeBlock(name) + "\n\n" + findContextName(name).doc
+	default:
+		if f := d.functionAt(pos.Line + 1); f != nil && f.Name.GetToken().GetPosition() == token.GetPosition() {
+			fn := function{d.path, f}
+			contents = codeBlock(fn.signature())
+		}
+	}
+	if contents == "" {
+		return nil, nil
+	}
+	return hover{Contents: markupContent{Kind: markupKindMarkdown, Value: contents}, Range: &r}, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	d, pos, err := s.documentAt(params)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]completionItem, 0)
+	seen := make(map[string]bool)
+	add := func(item completionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	i := d.tokenAt(pos)
+	if token := d.token(i); token != nil && token.Typ == lexer.IdentifierToken && isMethodName(d, i) {
+		i--
+	}
+	if token := d.token(i); token != nil && token.Typ == lexer.MethodCallToken && d.token(i-1) != nil {
+		object := d.token(i - 1).GetRawValue()
+		for _, b := range internalMethods[object] {
+			add(completionItem{Label: b.name, Kind: completionMethod, Detail: b.signature})
+		}
+		if _, ok := internalMethods[object]; ok {
+			return completionList{Items: items}, nil
+		}
+		for _, f := range s.methodCandidates(d, object, "") {
+			add(completionItem{Label: f.declaration.Name.Value, Kind: completionMethod, Detail: f.signature()})
+		}
+	} else {
+		for _, c := range contextNames {
+			add(completionItem{Label: c.name, Kind: completionVariable, Detail: c.doc})
+		}
+		for _, v := range d.variablesAt(pos.Line + 1) {
+			add(completionItem{Label: v.name, Kind: completionVariable, Detail: strings.TrimSpace("var " + v.name + " " + v.typ)})
+		}
+		for alias := range d.imports() {
+			add(completionItem{Label: alias, Kind: completionModule, Detail: "import"})
+		}
+		for _, k := range keywords {
+			add(completionItem{Label: k, Kind: completionKeyword})
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].Kind < items[b].Kind
+	})
+	return completionList{Items: items}, nil
+}
@@ -0,0 +1,285 @@
+package lsp
+
+import (
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/compiler"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/gmsl/parser"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type document struct {
+	uri    string
+	path   string
+	text   string
+	tokens []lexer.Token
+	class  *parser.Class
+}
+
+func newDocument(uri string, text string) *document {
+	d := &document{uri: uri, path: uriToPath(uri)}
+	d.update(text)
+	return d
+}
+
+func (d *document) update(text string) {
+	d.text = text
+	result := gmsl.Parse(d.path, text)
+	d.tokens = result.Tokens()
+	if result.Class != nil {
+		d.class = result.Class
+	}
+}
+
+func (d *document) diagnostics() []diagnostic {
+	result := make([]diagnostic, 0)
+	for _, diag := range gmsl.Compile(d.path, d.text).Diagnostics {
+		start := position{}
+		if diag.Line > 0 {
+			start = position{Line: diag.Line - 1, Character: diag.Column - 1}
+		}
+		end := position{Line: start.Line, Character: start.Character + 1}
+		result = append(result, diagnostic{
+			Range:    lspRange{start, end},
+			Severity: severityError,
+			Source:   "gmsl",
+			Message:  diag.Message,
+		})
+	}
+	return result
+}
+
+func tokenLength(t *lexer.Token) int {
+	if t.Typ == lexer.StringToken {
+		return len(t.GetRawValue()) + 2
+	}
+	return len(t.GetRawValue())
+}
+
+func tokenRange(t *lexer.Token) lspRange {
+	p := t.GetPosition()
+	start := position{Line: p.Line - 1, Character: p.Column - 1}
+	return lspRange{start, position{Line: start.Line, Character: start.Character + tokenLength(t)}}
+}
+
+// tokenAt returns the index of the token under the cursor, or of the token
+// ending right before it when the cursor sits at the end of a word.
+func (d *document) tokenAt(pos position) int {
+	found := -1
+	for i := range d.tokens {
+		r := tokenRange(&d.tokens[i])
+		if r.Start.Line != pos.Line || d.tokens[i].Typ == lexer.EofToken {
+			continue
+		}
+		if r.Start.Character <= pos.Character && pos.Character < r.End.Character {
+			return i
+		}
+		if r.End.Character == pos.Character {
+			found = i
+		}
+	}
+	return found
+}
+
+func (d *document) token(i int) *lexer.Token {
+	if i < 0 || i >= len(d.tokens) {
+		return nil
+	}
+	return &d.tokens[i]
+}
+
+func (d *document) imports() map[string]string {
+	result := make(map[string]string)
+	if d.class == nil {
+		return result
+	}
+	for _, i := range d.class.Imports {
+		switch i := i.(type) {
+		case *parser.SingleImportDeclaration:
+			result[path.Base(i.Name.Value)] = i.Name.Value
+		case *parser.ImportDeclarationList:
+			for _, name := range i.Imports {
+				result[path.Base(name.Value)] = name.Value
+			}
+		}
+	}
+	return result
+}
+
+func (d *document) functionAt(line int) *parser.FunctionDeclaration {
+	if d.class == nil {
+		return nil
+	}
+	for n := range d.class.Functions {
+		f := &d.class.Functions[n]
+		start := f.GetToken().GetPosition().Line
+		end := f.GetEndToken().GetPosition().Line
+		if start <= line && line <= end {
+			return f
+		}
+	}
+	return nil
+}
+
+type variable struct {
+	name  string
+	typ   string
+	token *lexer.Token
+}
+
+func (d *document) variablesAt(line int) []variable {
+	f := d.functionAt(line)
+	if f == nil {
+		return nil
+	}
+	result := make([]variable, 0)
+	for n := range d.class.Variables {
+		v := &d.class.Variables[n]
+		result = append(result, variable{v.GetVariableName(), v.GetType().Name, v.GetToken()})
+	}
+	for n := range f.Arguments {
+		a := &f.Arguments[n]
+		result = append(result, variable{a.Name.Value, a.Typ.Name, a.GetToken()})
+	}
+	return append(result, statementVariables(f.Statements, line)...)
+}
+
+func statementVariables(statements []parser.Statement, line int) []variable {
+	result := make([]variable, 0)
+	for _, s := range statements {
+		if s.GetToken().GetPosition().Line > line {
+			break
+		}
+		switch s := s.(type) {
+		case *parser.VariableDeclarationStatement:
+			result = append(result, variable{s.GetVariableName(), s.GetType().Name, s.GetToken()})
+		case *parser.VariableCreateAndAssignStatement:
+			result = append(result, variable{s.GetVariableName(), "", s.GetToken()})
+		case *parser.IfStatement:
+			result = append(result, statementVariables(s.Statements, line)...)
+			result = append(result, statementVariables(s.ElseStatements, line)...)
+		}
+	}
+	return result
+}
+
+type function struct {
+	path        string
+	declaration *parser.FunctionDeclaration
+}
+
+func (f *function) signature() string {
+	var b strings.Builder
+	b.WriteString("func ")
+	b.WriteString(f.declaration.Name.Value)
+	b.WriteString("(")
+	for n, a := range f.declaration.Arguments {
+		if n > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(a.Name.Value)
+		b.WriteString(" ")
+		b.WriteString(a.Typ.Name)
+	}
+	b.WriteString(")")
+	for _, r := range f.declaration.ReturnTypes {
+		b.WriteString(" ")
+		b.WriteString(r.Name)
+	}
+	return b.String()
+}
+
+func (f *function) location() location {
+	return location{Uri: pathToUri(f.path), Range: tokenRange(f.declaration.Name.GetToken())}
+}
+
+func (s *Server) classPath(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name)+compiler.SourceExtension)
+}
+
+func (s *Server) parseClass(filePath string) *parser.Class {
+	for _, d := range s.documents {
+		if d.path == filePath {
+			return d.class
+		}
+	}
+	return gmsl.ParseFile(filePath).Class
+}
+
+func functionsOf(filePath string, class *parser.Class) []function {
+	result := make([]function, 0)
+	if class == nil {
+		return result
+	}
+	for n := range class.Functions {
+		result = append(result, function{filePath, &class.Functions[n]})
+	}
+	return result
+}
+
+func (s *Server) workspaceFunctions() []function {
+	result := make([]function, 0)
+	if s.root == "" {
+		for _, d := range s.documents {
+			result = append(result, functionsOf(d.path, d.class)...)
+		}
+		return result
+	}
+	_ = filepath.WalkDir(s.root, func(p string, e fs.DirEntry, err error) error {
+		if err == nil && !e.IsDir() && strings.HasSuffix(p, compiler.SourceExtension) {
+			result = append(result, functionsOf(p, s.parseClass(p))...)
+		}
+		return nil
+	})
+	return result
+}
+
+// Methods called on an imported class resolve to that file, calls on context
+// objects may land in any class, so every function with that name matches.
+func (s *Server) methodCandidates(d *document, object string, method string) []function {
+	var functions []function
+	if imported, ok := d.imports()[object]; ok {
+		p := s.classPath(imported)
+		functions = functionsOf(p, s.parseClass(p))
+	} else {
+		functions = s.workspaceFunctions()
+	}
+	result := make([]function, 0)
+	for _, f := range functions {
+		if method == "" || f.declaration.Name.Value == method {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToUri(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String()
+}
+
+func findMudlibRoot(root string) string {
+	if root == "" {
+		return ""
+	}
+	mudlib := filepath.Join(root, "mudlib")
+	if info, err := os.Stat(mudlib); err == nil && info.IsDir() {
+		return mudlib
+	}
+	return root
+}
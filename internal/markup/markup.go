@@ -0,0 +1,261 @@
+// Package markup renders the colour tags mudlib code puts in its messages,
+// "{red}Danger!{/}", into ANSI escape codes for what the client can show.
+//
+// Tags are the colour names black, red, green, yellow, blue, magenta, cyan
+// and white, their bright_ variants, hex colours like {#ff8800}, any of
+// those with on_ in front for the background, {bold}, {underline} and {/}
+// to reset. {{ is a literal brace. Anything else in braces is left alone.
+package markup
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode is how much colour a client gets.
+type Mode int
+
+const (
+	// Plain strips the tags.
+	Plain Mode = iota
+	ANSI16
+	ANSI256
+	TrueColour
+)
+
+var modeNames = []string{"plain", "16 colours", "256 colours", "true colour"}
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return "Mode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return modeNames[m]
+}
+
+var colourNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// palette is how xterm shows the 16 colours, hex colours become the nearest
+// of them for 16 colour clients.
+var palette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+const reset = "\x1b[0m"
+
+// Render replaces the tags of a line with escape codes for mode. A line that
+// leaves a colour on gets a reset at its end, colours never bleed into the
+// next line.
+func Render(line string, mode Mode) string {
+	if !strings.Contains(line, "{") {
+		return line
+	}
+	var b strings.Builder
+	styled := false
+	for {
+		start := strings.IndexByte(line, '{')
+		if start < 0 {
+			b.WriteString(line)
+			break
+		}
+		b.WriteString(line[:start])
+		line = line[start:]
+		if strings.HasPrefix(line, "{{") {
+			b.WriteByte('{')
+			line = line[2:]
+			continue
+		}
+		end := strings.IndexByte(line, '}')
+		if end < 0 {
+			b.WriteString(line)
+			break
+		}
+		code, ok := escape(line[1:end], mode)
+		if !ok {
+			b.WriteByte('{')
+			line = line[1:]
+			continue
+		}
+		b.WriteString(code)
+		if mode != Plain {
+			styled = code != reset
+		}
+		line = line[end+1:]
+	}
+	if styled {
+		b.WriteString(reset)
+	}
+	return b.String()
+}
+
+// Escape doubles the braces of text from players so that Render shows it as
+// typed instead of reading tags in it.
+func Escape(text string) string {
+	return strings.ReplaceAll(text, "{", "{{")
+}
+
+// Strip removes the tags, what Render does for plain clients.
+func Strip(line string) string {
+	return Render(line, Plain)
+}
+
+// escape turns a tag into its escape code, ok is false for what is not a
+// tag.
+func escape(tag string, mode Mode) (code string, ok bool) {
+	tag = strings.ToLower(tag)
+	var sgr string
+	switch tag {
+	case "/":
+		sgr = "0"
+	case "bold":
+		sgr = "1"
+	case "underline":
+		sgr = "4"
+	default:
+		background := false
+		if rest, found := strings.CutPrefix(tag, "on_"); found {
+			tag, background = rest, true
+		}
+		if sgr, ok = colour(tag, background, mode); !ok {
+			return "", false
+		}
+	}
+	if mode == Plain {
+		return "", true
+	}
+	return "\x1b[" + sgr + "m", true
+}
+
+// colour gives the SGR parameters of a colour tag.
+func colour(tag string, background bool, mode Mode) (string, bool) {
+	if rest, found := strings.CutPrefix(tag, "#"); found {
+		rgb, ok := parseHex(rest)
+		if !ok {
+			return "", false
+		}
+		switch mode {
+		case TrueColour:
+			prefix := "38;2;"
+			if background {
+				prefix = "48;2;"
+			}
+			return prefix + strconv.Itoa(rgb[0]) + ";" + strconv.Itoa(rgb[1]) + ";" + strconv.Itoa(rgb[2]), true
+		case ANSI256:
+			prefix := "38;5;"
+			if background {
+				prefix = "48;5;"
+			}
+			return prefix + strconv.Itoa(to256(rgb)), true
+		default:
+			return basic(nearest16(rgb), background), true
+		}
+	}
+	bright := false
+	if rest, found := strings.CutPrefix(tag, "bright_"); found {
+		tag, bright = rest, true
+	}
+	for n, name := range colourNames {
+		if name == tag {
+			if bright {
+				n += 8
+			}
+			return basic(n, background), true
+		}
+	}
+	return "", false
+}
+
+// basic gives the SGR parameter of one of the 16 colours, 30-37 and 90-97
+// for the foreground, 40-47 and 100-107 for the background.
+func basic(n int, background bool) string {
+	base := 30
+	if n >= 8 {
+		base, n = 90, n-8
+	}
+	if background {
+		base += 10
+	}
+	return strconv.Itoa(base + n)
+}
+
+func parseHex(s string) ([3]int, bool) {
+	var rgb [3]int
+	if len(s) != 6 {
+		return rgb, false
+	}
+	for i := range rgb {
+		v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return rgb, false
+		}
+		rgb[i] = int(v)
+	}
+	return rgb, true
+}
+
+// to256 picks the colour of the 6x6x6 cube of the 256 colour palette, or
+// of its grey ramp for greys.
+func to256(rgb [3]int) int {
+	if rgb[0] == rgb[1] && rgb[1] == rgb[2] {
+		switch {
+		case rgb[0] < 8:
+			return 16
+		case rgb[0] > 238:
+			return 231
+		default:
+			return 232 + (rgb[0]-8)/10
+		}
+	}
+	level := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	return 16 + 36*level(rgb[0]) + 6*level(rgb[1]) + level(rgb[2])
+}
+
+func nearest16(rgb [3]int) int {
+	best, bestDistance := 0, -1
+	for n, p := range palette {
+		distance := 0
+		for i := range p {
+			distance += (p[i] - rgb[i]) * (p[i] - rgb[i])
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = n, distance
+		}
+	}
+	return best
+}
+
+// Wrap breaks text into lines of at most width characters at spaces, the
+// tags do not count. Words longer than a line get one of their own.
+func Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	paragraphs := strings.Split(text, "\n")
+	for i, paragraph := range paragraphs {
+		var b strings.Builder
+		length := 0
+		for _, word := range strings.Fields(paragraph) {
+			wordLength := utf8.RuneCountInString(Strip(word))
+			if length > 0 && length+1+wordLength > width {
+				b.WriteByte('\n')
+				length = 0
+			} else if length > 0 {
+				b.WriteByte(' ')
+				length++
+			}
+			b.WriteString(word)
+			length += wordLength
+		}
+		paragraphs[i] = b.String()
+	}
+	return strings.Join(paragraphs, "\n")
+}
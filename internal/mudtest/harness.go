@@ -0,0 +1,183 @@
+package mudtest
+
+import (
+	"goMud/internal/gmcp"
+	"goMud/internal/repl"
+	"goMud/internal/vm"
+	"strings"
+	"time"
+)
+
+// Harness runs mudlib code in a virtual machine of its own with a player
+// whose output is recorded instead of sent to a connection. Nothing runs the
+// machine's command loop, calls happen on the caller's goroutine and time
+// only passes when the test advances the clock.
+type Harness struct {
+	machine *vm.VirtualMachine
+	clock   *vm.ManualClock
+	context *repl.Context
+	player  *vm.Object
+	room    string
+	object  *vm.Object
+	output  []string
+	gmcp    []gmcp.Message
+	width   int
+	height  int
+}
+
+func New(mudlibPath string) *Harness {
+	h := &Harness{machine: vm.NewVirtualMachine(mudlibPath), clock: vm.NewManualClock(time.Unix(0, 0)), context: repl.NewContext(), width: 80, height: 24}
+	h.machine.SetClock(h.clock)
+	h.machine.SetDefaultContext(h.context)
+	h.player = h.newPlayer()
+	h.context.Set("player", h.player)
+	h.object = vm.NewObjectFromClass(*vm.NewEmptyClass("<room>"))
+	h.context.Set("room", h.object)
+	return h
+}
+
+func (h *Harness) newPlayer() *vm.Object {
+	class := vm.NewEmptyClass("<player>")
+	player := vm.NewObjectFromClass(*class)
+	class.RegisterInternalMethod("Send", 1, 0, func(values []vm.Value) []vm.Value {
+		h.output = append(h.output, values[0].String())
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("String", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(player.String())}
+	})
+	// The test player's client takes every GMCP package.
+	class.RegisterInternalMethod("SendGMCP", 2, 0, func(values []vm.Value) []vm.Value {
+		m := gmcp.Message{Package: values[0].String()}
+		if values[1].String() != "" {
+			data, err := vm.JSONData(values[1])
+			if err != nil {
+				panic(err)
+			}
+			m.Data = data
+		}
+		h.gmcp = append(h.gmcp, m)
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("GMCPSupports", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(1)}
+	})
+	class.RegisterInternalMethod("Width", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(h.width)}
+	})
+	class.RegisterInternalMethod("Height", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(h.height)}
+	})
+	class.RegisterInternalMethod("MoveTo", 1, 0, func(values []vm.Value) []vm.Value {
+		if err := h.SetRoom(values[0].String()); err != nil {
+			panic(err)
+		}
+		return []vm.Value{}
+	})
+	return player
+}
+
+// Load creates an object of the named mudlib class, compile errors come back
+// as an error instead of the panic the server would get.
+func (h *Harness) Load(name string) (object *vm.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = vm.RuntimeError(r)
+		}
+	}()
+	return h.machine.LoadObject(name), nil
+}
+
+// SetRoom puts the player into a room, like MoveTo without the mudlib.
+func (h *Harness) SetRoom(name string) error {
+	room, err := h.Load(name)
+	if err != nil {
+		return err
+	}
+	h.room, h.object = name, room
+	h.context.Set("room", room)
+	return h.machine.MoveObject(h.player, room, h.context)
+}
+
+// SetWindowSize sets what the player's Width and Height report, 80 by 24
+// until it is called.
+func (h *Harness) SetWindowSize(width int, height int) {
+	h.width, h.height = width, height
+}
+
+// Room returns the mudlib path of the room the player is in, "" until the
+// first SetRoom or MoveTo.
+func (h *Harness) Room() string {
+	return h.room
+}
+
+// Command runs a line the way the server runs what a player types, through
+// the actions of the objects around the player.
+func (h *Harness) Command(line string) (bool, error) {
+	return h.machine.Command(h.player, nil, line, h.context)
+}
+
+// SetDataPath sets where SaveObject and RestoreObject keep their files.
+func (h *Harness) SetDataPath(path string) {
+	h.machine.SetDataPath(path)
+}
+
+func (h *Harness) Player() *vm.Object {
+	return h.player
+}
+
+// Set puts another object in the context, for example an item.
+func (h *Harness) Set(name string, object *vm.Object) {
+	h.context.Set(name, object)
+}
+
+func (h *Harness) Call(object *vm.Object, method string, arguments ...vm.Value) ([]vm.Value, error) {
+	return h.machine.Call(object, method, arguments, h.context)
+}
+
+func (h *Harness) CallRoom(method string, arguments ...vm.Value) ([]vm.Value, error) {
+	return h.Call(h.object, method, arguments...)
+}
+
+// Advance moves the clock forward and runs the heartbeats and delayed calls
+// that came due.
+func (h *Harness) Advance(d time.Duration) {
+	h.clock.Advance(d)
+	h.machine.RunDueCalls()
+}
+
+// Output returns the lines sent to the player since the last ClearOutput.
+func (h *Harness) Output() []string {
+	return h.output
+}
+
+// ClearOutput forgets the lines and GMCP messages sent so far.
+func (h *Harness) ClearOutput() {
+	h.output = nil
+	h.gmcp = nil
+}
+
+// GMCP returns the GMCP messages of a package sent to the player since the
+// last ClearOutput.
+func (h *Harness) GMCP(name string) []gmcp.Message {
+	result := make([]gmcp.Message, 0)
+	for _, m := range h.gmcp {
+		if m.Package == name {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func (h *Harness) HasOutput(line string) bool {
+	for _, l := range h.output {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Harness) OutputString() string {
+	return strings.Join(h.output, "\n")
+}
@@ -0,0 +1,51 @@
+package mudtest
+
+import (
+	"goMud/internal/logging"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mudlibPath = "../../mudlib"
+
+// TestMudlib runs every _test.gms file of the mudlib, so go test fails with
+// them.
+func TestMudlib(t *testing.T) {
+	logging.SetAllLevels(slog.LevelWarn)
+	files := make([]string, 0)
+	err := filepath.WalkDir(mudlibPath, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && IsTestFile(path) {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files in", mudlibPath)
+	}
+
+	for _, path := range files {
+		name := filepath.ToSlash(strings.TrimPrefix(path, mudlibPath+string(filepath.Separator)))
+		t.Run(name, func(t *testing.T) {
+			results, err := RunFile(mudlibPath, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				t.Run(r.Name, func(t *testing.T) {
+					for _, m := range r.Messages {
+						t.Log(m)
+					}
+					if r.Failed {
+						t.Fail()
+					}
+				})
+			}
+		})
+	}
+}
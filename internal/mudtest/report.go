@@ -0,0 +1,49 @@
+package mudtest
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// Report prints results the way go test does, so the usual tooling around
+// go test output can read it. It returns whether every test passed.
+func Report(w io.Writer, path string, results []Result, elapsed time.Duration, verbose bool) bool {
+	file := filepath.Base(path)
+	passed := true
+	for _, r := range results {
+		if verbose {
+			fmt.Fprintf(w, "=== RUN   %s\n", r.Name)
+		}
+		status := "PASS"
+		if r.Failed {
+			status = "FAIL"
+			passed = false
+		}
+		switch {
+		case verbose:
+			printMessages(w, file, r)
+			fmt.Fprintf(w, "--- %s: %s (%.2fs)\n", status, r.Name, r.Elapsed.Seconds())
+		case r.Failed:
+			fmt.Fprintf(w, "--- %s: %s (%.2fs)\n", status, r.Name, r.Elapsed.Seconds())
+			printMessages(w, file, r)
+		}
+	}
+	if passed {
+		if verbose {
+			fmt.Fprintln(w, "PASS")
+		}
+		fmt.Fprintf(w, "ok  \t%s\t%.3fs\n", path, elapsed.Seconds())
+	} else {
+		fmt.Fprintln(w, "FAIL")
+		fmt.Fprintf(w, "FAIL\t%s\t%.3fs\n", path, elapsed.Seconds())
+	}
+	return passed
+}
+
+func printMessages(w io.Writer, file string, r Result) {
+	for _, m := range r.Messages {
+		fmt.Fprintf(w, "    %s: %s\n", file, m)
+	}
+}
@@ -0,0 +1,213 @@
+package mudtest
+
+import (
+	"errors"
+	"fmt"
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/compiler"
+	"goMud/internal/repl"
+	"goMud/internal/vm"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	TestFileSuffix = "_test" + compiler.SourceExtension
+	testPrefix     = "Test"
+	testType       = "test"
+)
+
+var errTestStopped = errors.New("test stopped by Fatal")
+
+type Result struct {
+	Name     string
+	Failed   bool
+	Elapsed  time.Duration
+	Messages []string
+}
+
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(path, TestFileSuffix)
+}
+
+// TestNames lists the functions of a test file that the runner calls, those
+// named TestXxx taking a single test argument.
+func TestNames(class *gmsl.Result) []string {
+	names := make([]string, 0)
+	if class.Class == nil {
+		return names
+	}
+	for _, f := range class.Class.Functions {
+		if strings.HasPrefix(f.Name.Value, testPrefix) && len(f.Arguments) == 1 && f.Arguments[0].Typ.Name == testType {
+			names = append(names, f.Name.Value)
+		}
+	}
+	return names
+}
+
+func className(mudlibPath string, path string) (string, error) {
+	rel, err := filepath.Rel(mudlibPath, path)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", errors.New(path + " is outside of " + mudlibPath)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, compiler.SourceExtension)), nil
+}
+
+// RunFile runs the tests of a _test.gms file, each in a fresh harness. When
+// the file sits next to the class it tests, room_a_test.gms next to
+// room_a.gms, the player starts in that room.
+func RunFile(mudlibPath string, path string, run func(name string) bool) ([]Result, error) {
+	result := gmsl.CompileFile(path)
+	if result.HasErrors() {
+		return nil, errors.New(result.Diagnostics[0].String())
+	}
+	class, err := className(mudlibPath, path)
+	if err != nil {
+		return nil, err
+	}
+	room := strings.TrimSuffix(class, "_test")
+	if _, err := os.Stat(filepath.Join(mudlibPath, filepath.FromSlash(room)+compiler.SourceExtension)); err != nil {
+		room = ""
+	}
+
+	results := make([]Result, 0)
+	for _, name := range TestNames(result) {
+		if run != nil && !run(name) {
+			continue
+		}
+		results = append(results, runTest(mudlibPath, class, room, name))
+	}
+	return results, nil
+}
+
+func runTest(mudlibPath string, class string, room string, name string) Result {
+	start := time.Now()
+	r := &Result{Name: name}
+	h := New(mudlibPath)
+	err := func() error {
+		data, err := os.MkdirTemp("", "mudtest-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(data)
+		h.SetDataPath(data)
+		if room != "" {
+			if err := h.SetRoom(room); err != nil {
+				return err
+			}
+		}
+		object, err := h.Load(class)
+		if err != nil {
+			return err
+		}
+		_, err = h.Call(object, name, *vm.NewObjectValue(newTestObject(h, r)))
+		return err
+	}()
+	if err != nil && err != errTestStopped {
+		r.Failed = true
+		r.Messages = append(r.Messages, "panic: "+err.Error())
+	}
+	r.Elapsed = time.Since(start)
+	return *r
+}
+
+func newTestObject(h *Harness, r *Result) *vm.Object {
+	class := vm.NewEmptyClass("<test>")
+	errorf := func(message string) {
+		r.Failed = true
+		r.Messages = append(r.Messages, message)
+	}
+	class.RegisterInternalMethod("Log", 1, 0, func(values []vm.Value) []vm.Value {
+		r.Messages = append(r.Messages, values[0].String())
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Error", 1, 0, func(values []vm.Value) []vm.Value {
+		errorf(values[0].String())
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Fatal", 1, 0, func(values []vm.Value) []vm.Value {
+		errorf(values[0].String())
+		panic(errTestStopped)
+	})
+	class.RegisterInternalMethod("Equal", 2, 0, func(values []vm.Value) []vm.Value {
+		got, want := repl.FormatValue(values[0]), repl.FormatValue(values[1])
+		if got != want {
+			errorf("got " + got + ", want " + want)
+		}
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("ExpectOutput", 1, 0, func(values []vm.Value) []vm.Value {
+		line := values[0].String()
+		if !h.HasOutput(line) {
+			errorf(fmt.Sprintf("no output line %q, got %q", line, h.Output()))
+		}
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Output", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(h.OutputString())}
+	})
+	class.RegisterInternalMethod("ClearOutput", 0, 0, func(values []vm.Value) []vm.Value {
+		h.ClearOutput()
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("Command", 1, 1, func(values []vm.Value) []vm.Value {
+		handled, err := h.Command(values[0].String())
+		if err != nil {
+			errorf(err.Error())
+			panic(errTestStopped)
+		}
+		if handled {
+			return []vm.Value{vm.NewNumberValue(1)}
+		}
+		return []vm.Value{vm.NewNumberValue(0)}
+	})
+	class.RegisterInternalMethod("Room", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(h.Room())}
+	})
+	class.RegisterInternalMethod("Advance", 1, 0, func(values []vm.Value) []vm.Value {
+		n, ok := values[0].(vm.NumberValue)
+		if !ok {
+			errorf("Advance wants a number of seconds, got " + repl.FormatValue(values[0]))
+			panic(errTestStopped)
+		}
+		h.Advance(time.Duration(n.Value) * time.Second)
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("SentGMCP", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(len(h.GMCP(values[0].String())))}
+	})
+	class.RegisterInternalMethod("GMCPData", 1, 1, func(values []vm.Value) []vm.Value {
+		sent := h.GMCP(values[0].String())
+		if len(sent) == 0 || sent[len(sent)-1].Data == nil {
+			return []vm.Value{vm.NewNumberValue(0)}
+		}
+		o, err := vm.NewJSONObject(sent[len(sent)-1].Data)
+		if err != nil {
+			errorf(err.Error())
+			panic(errTestStopped)
+		}
+		return []vm.Value{*vm.NewObjectValue(o)}
+	})
+	class.RegisterInternalMethod("SetWidth", 1, 0, func(values []vm.Value) []vm.Value {
+		n, ok := values[0].(vm.NumberValue)
+		if !ok {
+			errorf("SetWidth wants a number of characters, got " + repl.FormatValue(values[0]))
+			panic(errTestStopped)
+		}
+		h.SetWindowSize(n.Value, h.height)
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("SetRoom", 1, 0, func(values []vm.Value) []vm.Value {
+		if err := h.SetRoom(values[0].String()); err != nil {
+			errorf(err.Error())
+			panic(errTestStopped)
+		}
+		return []vm.Value{}
+	})
+	return vm.NewObjectFromClass(*class)
+}
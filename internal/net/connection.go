@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"compress/zlib"
 	"encoding/hex"
-	"fmt"
+	"log/slog"
 	"net"
+	"sync/atomic"
 )
 
 type ConnectionCommandType int
@@ -15,6 +16,10 @@ const (
 	StartCompression
 	StopCompression
 	CloseConnection
+	// ConcealInput keeps what the client sends out of the log until
+	// RevealInput, the client is typing a password.
+	ConcealInput
+	RevealInput
 )
 
 type ConnectionCommand struct {
@@ -30,25 +35,27 @@ type ConnectionRead struct {
 type ZLibContext struct {
 	zLibWriter *zlib.Writer
 	payload    *bytes.Buffer
+	logger     *slog.Logger
 }
 
-func NewZLibContext() ZLibContext {
+func NewZLibContext(logger *slog.Logger) ZLibContext {
 	buf := &bytes.Buffer{}
 	return ZLibContext{
 		zLibWriter: zlib.NewWriter(buf),
 		payload:    buf,
+		logger:     logger,
 	}
 }
 
 func (z ZLibContext) Compress(data []byte) []byte {
 	_, err := z.zLibWriter.Write(data)
 	if err != nil {
-		fmt.Println("Error compressing data:", err)
+		z.logger.Error("Error compressing data", "error", err)
 		return nil
 	}
 	err = z.zLibWriter.Flush()
 	if err != nil {
-		fmt.Println("Error flushing data:", err)
+		z.logger.Error("Error flushing data", "error", err)
 		return nil
 	}
 	result := z.payload.Bytes()
@@ -60,8 +67,11 @@ type Connection struct {
 	conn        net.Conn
 	command     chan ConnectionCommand
 	read        chan ConnectionRead
+	closed      chan struct{}
 	compressed  bool
+	concealed   *atomic.Bool
 	zlibContext ZLibContext
+	logger      *slog.Logger
 }
 
 func (c Connection) HandleConnection() {
@@ -72,19 +82,26 @@ func (c Connection) HandleConnection() {
 func (c Connection) handleAsyncWrite() {
 	for {
 		select {
+		case <-c.closed:
+			c.conn.Close()
+			return
 		case command := <-c.command:
 			switch command.command {
 			case SendData:
 				c.sendData(command.data)
 			case StartCompression:
-				c.zlibContext = NewZLibContext()
+				c.zlibContext = NewZLibContext(c.logger)
 				c.compressed = true
 			case StopCompression:
 				c.compressed = false
+			case ConcealInput:
+				c.concealed.Store(true)
+			case RevealInput:
+				c.concealed.Store(false)
 			case CloseConnection:
 				err := c.conn.Close()
 				if err != nil {
-					fmt.Println("Error closing connection:", err)
+					c.logger.Warn("Error closing connection", "error", err)
 				}
 				return
 			}
@@ -97,16 +114,21 @@ func (c Connection) handleAsyncRead() {
 		buf := make([]byte, 1024)
 		length, err := c.conn.Read(buf)
 		if err != nil {
-			fmt.Println("Error reading:", err)
+			c.logger.Info("Connection read ended", "error", err)
+			close(c.closed)
 			return
 		}
-		fmt.Println("Data received: " + hex.EncodeToString(buf[:length]))
+		if c.concealed.Load() {
+			c.logger.Debug("Data received", "length", length)
+		} else {
+			c.logger.Debug("Data received", "data", hex.EncodeToString(buf[:length]))
+		}
 		c.read <- ConnectionRead{length: length, data: buf[:length]}
 	}
 }
 
 func (c Connection) sendData(data []byte) {
-	fmt.Println("Data to send: " + hex.EncodeToString(data))
+	c.logger.Debug("Data to send", "data", hex.EncodeToString(data))
 	var payload bytes.Buffer
 
 	if c.compressed == true {
@@ -116,15 +138,16 @@ func (c Connection) sendData(data []byte) {
 		payload.Write(data)
 	}
 
-	fmt.Println("Data sent: " + hex.EncodeToString(payload.Bytes()))
-	fmt.Println(hex.EncodeToString(payload.Bytes()))
+	c.logger.Debug("Data sent", "data", hex.EncodeToString(payload.Bytes()), "compressed", c.compressed)
 	_, err := c.conn.Write(payload.Bytes())
 	if err != nil {
-		fmt.Println("Error writing data:", err)
+		c.logger.Warn("Error writing data", "error", err)
 		return
 	}
 }
 
-func NewConnection(conn net.Conn, command chan ConnectionCommand, read chan ConnectionRead) *Connection {
-	return &Connection{conn: conn, command: command, compressed: false, read: read}
+// NewConnection reads and writes conn until reading fails, then it closes
+// closed and everyone using the connection lets go of it.
+func NewConnection(conn net.Conn, command chan ConnectionCommand, read chan ConnectionRead, closed chan struct{}, logger *slog.Logger) *Connection {
+	return &Connection{conn: conn, command: command, compressed: false, concealed: &atomic.Bool{}, read: read, closed: closed, logger: logger}
 }
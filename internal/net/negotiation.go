@@ -0,0 +1,238 @@
+package net
+
+import (
+	"bytes"
+	"encoding/binary"
+	"goMud/internal/gmcp"
+	"goMud/internal/markup"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TTYPE subnegotiation commands.
+const (
+	terminalTypeIs   byte = 0
+	terminalTypeSend byte = 1
+)
+
+// MTTS flags, https://tintin.mudhalla.net/protocols/mtts/.
+const (
+	MTTSANSI         = 1
+	MTTSVT100        = 2
+	MTTSUTF8         = 4
+	MTTS256Colours   = 8
+	MTTSMouse        = 16
+	MTTSOSCPalette   = 32
+	MTTSScreenReader = 64
+	MTTSProxy        = 128
+	MTTSTrueColour   = 256
+	MTTSMNES         = 512
+	MTTSMSLP         = 1024
+	MTTSSSL          = 2048
+)
+
+// Default window size until the client reports one with NAWS.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// terminalInfo is what a client told about itself.
+type terminalInfo struct {
+	mutex        sync.Mutex
+	width        int
+	height       int
+	clientName   string
+	terminalType string
+	flags        int
+	// gmcp is set once the client agreed to GMCP.
+	gmcp bool
+	// replies counts the TTYPE answers, each request makes the client cycle
+	// to its next one: client name, terminal type and MTTS flags.
+	replies int
+}
+
+func newTerminalInfo() *terminalInfo {
+	return &terminalInfo{width: DefaultWidth, height: DefaultHeight}
+}
+
+// processSubNegotiation handles a complete subnegotiation, data is what came
+// between IAC SB and IAC SE with doubled IACs undone.
+func (t Telnet) processSubNegotiation(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	switch TelnetOptionByte(data[0]) {
+	case NAWS:
+		if len(data) != 5 {
+			t.logger.Debug("Malformed NAWS", "data", data)
+			return
+		}
+		t.setWindowSize(int(binary.BigEndian.Uint16(data[1:3])), int(binary.BigEndian.Uint16(data[3:5])))
+	case TerminalType:
+		if len(data) < 2 || data[1] != terminalTypeIs {
+			return
+		}
+		if t.terminalTypeReply(string(data[2:])) {
+			t.requestTerminalType()
+		}
+	case GMCP:
+		m, err := gmcp.Parse(data[1:])
+		if err != nil {
+			t.logger.Debug("Malformed GMCP", "data", string(data[1:]))
+			return
+		}
+		select {
+		case t.gmcp_handler <- m:
+		case <-t.closed:
+		}
+	}
+}
+
+func (t Telnet) setGMCP(enabled bool) {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	t.info.gmcp = enabled
+}
+
+// GMCPEnabled tells whether the client agreed to GMCP.
+func (t Telnet) GMCPEnabled() bool {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	return t.info.gmcp
+}
+
+// SendGMCP sends a GMCP message after the lines already sent, nothing when
+// the client did not agree to GMCP.
+func (t Telnet) SendGMCP(m gmcp.Message) {
+	select {
+	case t.gmcp_sender <- m:
+	case <-t.closed:
+	}
+}
+
+func (t Telnet) sendGMCP(m gmcp.Message) {
+	if !t.GMCPEnabled() {
+		return
+	}
+	data := []byte{byte(InterpretAsCommand), byte(SubNegotiation), byte(GMCP)}
+	// IAC in the payload is doubled, JSON text has none but a package name
+	// or string might
+	data = append(data, bytes.ReplaceAll(m.Bytes(), []byte{byte(InterpretAsCommand)}, []byte{byte(InterpretAsCommand), byte(InterpretAsCommand)})...)
+	data = append(data, byte(InterpretAsCommand), byte(SubNegotiationEnd))
+	t.send(ConnectionCommand{command: SendData, data: data})
+}
+
+// GMCP delivers the client's GMCP messages.
+func (t Telnet) GMCP() <-chan gmcp.Message {
+	return t.gmcp_handler
+}
+
+func (t Telnet) setWindowSize(width int, height int) {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	// 0 means the client does not know, keep the default then.
+	if width > 0 {
+		t.info.width = width
+	}
+	if height > 0 {
+		t.info.height = height
+	}
+	t.logger.Debug("Window size", "width", t.info.width, "height", t.info.height)
+}
+
+func (t Telnet) requestTerminalType() {
+	t.send(ConnectionCommand{
+		command: SendData,
+		data: []byte{
+			byte(InterpretAsCommand), byte(SubNegotiation), byte(TerminalType), terminalTypeSend,
+			byte(InterpretAsCommand), byte(SubNegotiationEnd),
+		},
+	})
+}
+
+// terminalTypeReply records one TTYPE answer and tells whether to ask for
+// the next. Clients without MTTS repeat their only answer, which ends the
+// cycle as well.
+func (t Telnet) terminalTypeReply(reply string) (more bool) {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	t.info.replies++
+	t.logger.Debug("Terminal type", "reply", reply, "n", t.info.replies)
+	if digits, found := strings.CutPrefix(reply, "MTTS "); found {
+		if flags, err := strconv.Atoi(digits); err == nil {
+			t.info.flags = flags
+		}
+		t.SetColourCapability(colourCapability(t.info.terminalType, t.info.flags))
+		return false
+	}
+	switch t.info.replies {
+	case 1:
+		t.info.clientName, t.info.terminalType = reply, reply
+		t.SetColourCapability(colourCapability(reply, 0))
+		return true
+	case 2:
+		if reply == t.info.clientName {
+			return false
+		}
+		t.info.terminalType = reply
+		t.SetColourCapability(colourCapability(reply, 0))
+		return true
+	}
+	return false
+}
+
+// colourCapability tells how much colour a client can show from its MTTS
+// flags or, without them, the name of its terminal type.
+func colourCapability(terminalType string, flags int) markup.Mode {
+	switch {
+	case flags&MTTSTrueColour != 0:
+		return markup.TrueColour
+	case flags&MTTS256Colours != 0:
+		return markup.ANSI256
+	case flags&MTTSANSI != 0:
+		return markup.ANSI16
+	case flags != 0:
+		return markup.Plain
+	}
+	terminalType = strings.ToLower(terminalType)
+	switch {
+	case strings.Contains(terminalType, "truecolor"), strings.Contains(terminalType, "24bit"):
+		return markup.TrueColour
+	case strings.Contains(terminalType, "256"):
+		return markup.ANSI256
+	case terminalType == "dumb":
+		return markup.Plain
+	}
+	return markup.ANSI16
+}
+
+// WindowSize is the client's window in characters, 80 by 24 until it tells
+// with NAWS.
+func (t Telnet) WindowSize() (width int, height int) {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	return t.info.width, t.info.height
+}
+
+// ClientName is the first TTYPE answer, like MUDLET, empty without TTYPE.
+func (t Telnet) ClientName() string {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	return t.info.clientName
+}
+
+// TerminalType is the terminal the client emulates, like XTERM-256COLOR.
+func (t Telnet) TerminalType() string {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	return t.info.terminalType
+}
+
+// TerminalFlags are the client's MTTS flags, 0 when it sent none.
+func (t Telnet) TerminalFlags() int {
+	t.info.mutex.Lock()
+	defer t.info.mutex.Unlock()
+	return t.info.flags
+}
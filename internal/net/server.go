@@ -1,15 +1,19 @@
 package net
 
 import (
-	"fmt"
 	"goMud/internal/game"
+	"goMud/internal/gmcp"
+	"goMud/internal/logging"
 	"goMud/internal/vm"
 	"net"
 	"os"
 )
 
+var logger = logging.Logger(logging.Net)
+
 type Server struct {
-	listener net.Listener
+	listener         net.Listener
+	lastConnectionId int
 }
 
 func NewServer() *Server {
@@ -21,31 +25,37 @@ func (s *Server) Start() {
 	s.listener = listener
 
 	if err != nil {
-		fmt.Println("Error listening:", err)
+		logger.Error("Error listening", "error", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Server listening on port 2323")
+	logger.Info("Server listening", "address", listener.Addr().String())
 	virtialMachine := vm.GetVirtualMachine()
 	go virtialMachine.Run()
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			fmt.Println("Error accepting:", err)
+			logger.Warn("Error accepting", "error", err)
 			continue
 		}
+		s.lastConnectionId++
+		id := s.lastConnectionId
+		connectionLogger := logger.With("conn", id)
+		connectionLogger.Info("Connection accepted", "remote", conn.RemoteAddr().String())
 
 		connectionChannel := make(chan ConnectionCommand)
 		readChannel := make(chan ConnectionRead)
+		closedChannel := make(chan struct{})
 
-		tConnection := NewConnection(conn, connectionChannel, readChannel)
+		tConnection := NewConnection(conn, connectionChannel, readChannel, closedChannel, connectionLogger)
 		lineHandlerChannel := make(chan string)
 		lineSenderChannel := make(chan string)
-		tTelnet := NewTelnet(connectionChannel, readChannel, lineHandlerChannel, lineSenderChannel)
+		gmcpChannel := make(chan gmcp.Message)
+		tTelnet := NewTelnet(connectionChannel, readChannel, closedChannel, lineHandlerChannel, lineSenderChannel, gmcpChannel, connectionLogger)
 
 		go tConnection.HandleConnection()
 		go tTelnet.HandleConnection()
-		game.NewHandler(lineHandlerChannel, lineSenderChannel)
+		game.NewHandler(id, lineHandlerChannel, lineSenderChannel, tTelnet)
 	}
 }
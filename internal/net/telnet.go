@@ -1,6 +1,11 @@
 package net
 
-import "log"
+import (
+	"goMud/internal/gmcp"
+	"goMud/internal/markup"
+	"log/slog"
+	"sync"
+)
 
 type TelnetState int
 
@@ -9,16 +14,45 @@ const (
 	ProcessCommandState
 	ProcessOptionState
 	SubNegotiationState
+	// SubNegotiationCommandState follows an IAC inside a subnegotiation.
+	SubNegotiationCommandState
+	// DiscardState drops the rest of a subnegotiation that was too long, up
+	// to its IAC SE.
+	DiscardState
+	// DiscardCommandState follows an IAC while discarding.
+	DiscardCommandState
 )
 
+// maxSubNegotiation caps the bytes buffered for one subnegotiation, a
+// client that sends more has the whole subnegotiation discarded.
+const maxSubNegotiation = 4096
+
+// Telnet speaks the telnet protocol for one connection. It is passed by
+// value, what the copies share lives behind pointers.
 type Telnet struct {
 	conn_command  chan ConnectionCommand
 	conn_read     chan ConnectionRead
 	state         TelnetState
 	buffer        []byte
 	commandBuffer []byte
+	subBuffer     []byte
 	line_handler  chan string
 	line_sender   chan string
+	gmcp_handler  chan gmcp.Message
+	gmcp_sender   chan gmcp.Message
+	closed        chan struct{}
+	hangup        chan struct{}
+	colour        *colourSettings
+	info          *terminalInfo
+	logger        *slog.Logger
+}
+
+// colourSettings is how lines sent to a session are coloured.
+type colourSettings struct {
+	mutex   sync.Mutex
+	enabled bool
+	// capability is the most the client is known to show.
+	capability markup.Mode
 }
 
 type TelnetCommandByte byte
@@ -37,10 +71,17 @@ const (
 type TelnetOptionByte byte
 
 const (
-	MCCP2 TelnetOptionByte = 86
+	Echo         TelnetOptionByte = 1
+	TerminalType TelnetOptionByte = 24
+	NAWS         TelnetOptionByte = 31
+	MCCP2        TelnetOptionByte = 86
+	GMCP         TelnetOptionByte = 201
 )
 
-func NewTelnet(conn_command chan ConnectionCommand, conn_read chan ConnectionRead, line_handler chan string, line_sender chan string) *Telnet {
+// NewTelnet speaks telnet over a connection. Once closed is closed it closes
+// line_handler, telling the game the connection is gone. GMCP messages from
+// the client go to gmcp_handler.
+func NewTelnet(conn_command chan ConnectionCommand, conn_read chan ConnectionRead, closed chan struct{}, line_handler chan string, line_sender chan string, gmcp_handler chan gmcp.Message, logger *slog.Logger) *Telnet {
 	return &Telnet{
 		conn_command:  conn_command,
 		conn_read:     conn_read,
@@ -49,77 +90,140 @@ func NewTelnet(conn_command chan ConnectionCommand, conn_read chan ConnectionRea
 		commandBuffer: make([]byte, 0),
 		line_handler:  line_handler,
 		line_sender:   line_sender,
+		gmcp_handler:  gmcp_handler,
+		gmcp_sender:   make(chan gmcp.Message),
+		closed:        closed,
+		hangup:        make(chan struct{}),
+		colour:        &colourSettings{enabled: true, capability: markup.ANSI16},
+		info:          newTerminalInfo(),
+		logger:        logger,
 	}
 }
 
 func (t Telnet) HandleConnection() {
 	go t.handleAsyncRead()
 	go t.handleLines()
-	t.conn_command <- ConnectionCommand{
+	t.send(ConnectionCommand{
 		command: SendData,
 		data: []byte{
 			byte(InterpretAsCommand),
 			byte(Will),
 			byte(MCCP2),
+			byte(InterpretAsCommand),
+			byte(Do),
+			byte(NAWS),
+			byte(InterpretAsCommand),
+			byte(Do),
+			byte(TerminalType),
+			byte(InterpretAsCommand),
+			byte(Will),
+			byte(GMCP),
 		},
-	}
+	})
 }
 
 func (t Telnet) handleAsyncRead() {
 	for {
 		select {
+		case <-t.closed:
+			close(t.line_handler)
+			return
 		case read := <-t.conn_read:
-			for _, b := range read.data {
-				switch t.state {
-				case SendDataState:
-					switch b {
-					case byte(InterpretAsCommand):
-						t.state = ProcessCommandState
-						t.commandBuffer = make([]byte, 1)
-						t.commandBuffer[0] = b
-					case 0, 13:
-						// ignore null and carriage return
-					case 10:
-						// convert buffer to string and handle in handleLine method and clear buffer
-						t.handleLine(string(t.buffer))
-						t.buffer = make([]byte, 0)
-					default:
-						// add byte to buffer
-						t.buffer = append(t.buffer, b)
-					}
-				case ProcessCommandState:
-					switch b {
-					case byte(Will), byte(Wont), byte(Do), byte(Dont):
-						t.state = ProcessOptionState
-						t.commandBuffer = append(t.commandBuffer, b)
-					case byte(SubNegotiation):
-						t.state = SubNegotiationState
-						t.commandBuffer = append(t.commandBuffer, b)
-					case byte(NoOperation):
-						t.state = SendDataState
-					case byte(InterpretAsCommand):
-						t.state = SendDataState
-						t.buffer = append(t.buffer, b)
-					default:
-						t.state = SendDataState
-					}
-				case ProcessOptionState:
-					t.state = SendDataState
-					t.commandBuffer = append(t.commandBuffer, b)
-					t.processOption(TelnetCommandByte(t.commandBuffer[1]), TelnetOptionByte(t.commandBuffer[2]))
-				case SubNegotiationState:
-					// pass
-				}
+			t.receive(read.data)
+		}
+	}
+}
+
+// receive runs the bytes read from the connection through the telnet state
+// machine.
+func (t *Telnet) receive(data []byte) {
+	for _, b := range data {
+		switch t.state {
+		case SendDataState:
+			switch b {
+			case byte(InterpretAsCommand):
+				t.state = ProcessCommandState
+				t.commandBuffer = make([]byte, 1)
+				t.commandBuffer[0] = b
+			case 0, 13:
+				// ignore null and carriage return
+			case 10:
+				// convert buffer to string and handle in handleLine method and clear buffer
+				t.handleLine(string(t.buffer))
+				t.buffer = make([]byte, 0)
+			default:
+				// add byte to buffer
+				t.buffer = append(t.buffer, b)
+			}
+		case ProcessCommandState:
+			switch b {
+			case byte(Will), byte(Wont), byte(Do), byte(Dont):
+				t.state = ProcessOptionState
+				t.commandBuffer = append(t.commandBuffer, b)
+			case byte(SubNegotiation):
+				t.state = SubNegotiationState
+				t.subBuffer = make([]byte, 0)
+			case byte(NoOperation):
+				t.state = SendDataState
+			case byte(InterpretAsCommand):
+				t.state = SendDataState
+				t.buffer = append(t.buffer, b)
+			default:
+				t.state = SendDataState
+			}
+		case ProcessOptionState:
+			t.state = SendDataState
+			t.commandBuffer = append(t.commandBuffer, b)
+			t.processOption(TelnetCommandByte(t.commandBuffer[1]), TelnetOptionByte(t.commandBuffer[2]))
+		case SubNegotiationState:
+			if b == byte(InterpretAsCommand) {
+				t.state = SubNegotiationCommandState
+			} else {
+				t.bufferSubNegotiation(b)
+			}
+		case SubNegotiationCommandState:
+			switch b {
+			case byte(SubNegotiationEnd):
+				t.state = SendDataState
+				t.processSubNegotiation(t.subBuffer)
+			case byte(InterpretAsCommand):
+				// a doubled IAC is a data byte
+				t.state = SubNegotiationState
+				t.bufferSubNegotiation(b)
+			default:
+				t.state = SubNegotiationState
+			}
+		case DiscardState:
+			if b == byte(InterpretAsCommand) {
+				t.state = DiscardCommandState
+			}
+		case DiscardCommandState:
+			if b == byte(SubNegotiationEnd) {
+				t.state = SendDataState
+			} else {
+				t.state = DiscardState
 			}
 		}
 	}
 }
 
+// bufferSubNegotiation adds a byte to the subnegotiation being read. One
+// that outgrows maxSubNegotiation is dropped up to its end.
+func (t *Telnet) bufferSubNegotiation(b byte) {
+	if len(t.subBuffer) >= maxSubNegotiation {
+		t.logger.Warn("Subnegotiation too long, discarding it", "limit", maxSubNegotiation)
+		t.subBuffer = nil
+		t.state = DiscardState
+		return
+	}
+	t.subBuffer = append(t.subBuffer, b)
+}
+
 func (t Telnet) handleLine(s string) {
 	if t.line_handler != nil {
 		t.line_handler <- s
 	} else {
-		log.Println("No line handler set for telnet, line sent:", s)
+		t.logger.Warn("No line handler set for telnet", "line", s)
 	}
 }
 
@@ -128,7 +232,7 @@ func (t Telnet) processOption(commandByte TelnetCommandByte, optionByte TelnetOp
 	case Will, Do:
 		switch optionByte {
 		case MCCP2:
-			t.conn_command <- ConnectionCommand{
+			t.send(ConnectionCommand{
 				command: SendData,
 				data: []byte{
 					byte(InterpretAsCommand),
@@ -137,27 +241,110 @@ func (t Telnet) processOption(commandByte TelnetCommandByte, optionByte TelnetOp
 					byte(InterpretAsCommand),
 					byte(SubNegotiationEnd),
 				},
+			})
+			t.send(ConnectionCommand{command: StartCompression})
+		case TerminalType:
+			if commandByte == Will {
+				t.requestTerminalType()
+			}
+		case GMCP:
+			if commandByte == Do {
+				t.setGMCP(true)
 			}
-			t.conn_command <- ConnectionCommand{command: StartCompression}
 		}
 	case Wont, Dont:
 		switch optionByte {
 		case MCCP2:
-			t.conn_command <- ConnectionCommand{command: StopCompression}
+			t.send(ConnectionCommand{command: StopCompression})
+		case Echo:
+			t.logger.Debug("Client refused server echo")
+		case GMCP:
+			t.setGMCP(false)
 		}
 	}
 }
 
+// SetEcho turns the client's local echo on or off. Offering to echo
+// ourselves, IAC WILL ECHO, makes clients stop echoing, and since nothing is
+// echoed back the input stays hidden. The client's DO or DONT needs no
+// answer. Turning echo back on also ends the line the client did not echo.
+// Hidden input is kept out of the log.
+func (t Telnet) SetEcho(enabled bool) {
+	if enabled {
+		t.send(ConnectionCommand{command: SendData, data: []byte{byte(InterpretAsCommand), byte(Wont), byte(Echo), '\r', '\n'}})
+		t.send(ConnectionCommand{command: RevealInput})
+		return
+	}
+	t.send(ConnectionCommand{command: ConcealInput})
+	t.send(ConnectionCommand{command: SendData, data: []byte{byte(InterpretAsCommand), byte(Will), byte(Echo)}})
+}
+
+// send hands a command to the connection, dropping it once the connection
+// is gone.
+func (t Telnet) send(command ConnectionCommand) {
+	select {
+	case t.conn_command <- command:
+	case <-t.closed:
+	}
+}
+
+// Close hangs up after the lines already sent.
+func (t Telnet) Close() {
+	select {
+	case t.hangup <- struct{}{}:
+	case <-t.closed:
+	}
+}
+
+func (t Telnet) Closed() <-chan struct{} {
+	return t.closed
+}
+
+// SendLine sends a line with its colour markup rendered for the session.
 func (t Telnet) SendLine(line string) {
-	t.conn_command <- ConnectionCommand{
+	t.send(ConnectionCommand{
 		command: SendData,
-		data:    []byte(line + "\n"),
+		data:    []byte(markup.Render(line, t.ColourMode()) + "\n"),
+	})
+}
+
+// SetColour turns colour on or off for the session, lines are stripped of
+// their markup while it is off.
+func (t Telnet) SetColour(enabled bool) {
+	t.colour.mutex.Lock()
+	defer t.colour.mutex.Unlock()
+	t.colour.enabled = enabled
+}
+
+// SetColourCapability records how much colour the client can show. Clients
+// that say nothing get 16 colours.
+func (t Telnet) SetColourCapability(mode markup.Mode) {
+	t.colour.mutex.Lock()
+	defer t.colour.mutex.Unlock()
+	t.colour.capability = mode
+}
+
+// ColourMode is how lines are rendered, Plain while colour is off.
+func (t Telnet) ColourMode() markup.Mode {
+	t.colour.mutex.Lock()
+	defer t.colour.mutex.Unlock()
+	if !t.colour.enabled {
+		return markup.Plain
 	}
+	return t.colour.capability
 }
 
 func (t Telnet) handleLines() {
 	for {
-		line := <-t.line_sender
-		t.SendLine(line)
+		select {
+		case <-t.closed:
+			return
+		case line := <-t.line_sender:
+			t.SendLine(line)
+		case m := <-t.gmcp_sender:
+			t.sendGMCP(m)
+		case <-t.hangup:
+			t.send(ConnectionCommand{command: CloseConnection})
+		}
 	}
 }
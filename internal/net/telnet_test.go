@@ -0,0 +1,42 @@
+package net
+
+import (
+	"bytes"
+	"goMud/internal/gmcp"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestTelnet() (*Telnet, chan string) {
+	lines := make(chan string, 16)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t := NewTelnet(make(chan ConnectionCommand, 16), nil, nil, lines, nil, make(chan gmcp.Message, 16), logger)
+	return t, lines
+}
+
+func TestOversizedSubNegotiationIsDiscarded(t *testing.T) {
+	telnet, lines := newTestTelnet()
+	data := []byte{byte(InterpretAsCommand), byte(SubNegotiation), byte(GMCP)}
+	data = append(data, bytes.Repeat([]byte("x"), maxSubNegotiation+10)...)
+	data = append(data, []byte("\nquit\n")...)
+	data = append(data, byte(InterpretAsCommand), byte(InterpretAsCommand), 'y')
+	data = append(data, byte(InterpretAsCommand), byte(SubNegotiationEnd))
+	data = append(data, []byte("look\n")...)
+	telnet.receive(data)
+
+	if telnet.state != SendDataState {
+		t.Fatalf("state = %d, want SendDataState", telnet.state)
+	}
+	select {
+	case line := <-lines:
+		if line != "look" {
+			t.Fatalf("first line = %q, want %q", line, "look")
+		}
+	default:
+		t.Fatal("no line after the discarded subnegotiation")
+	}
+	if len(lines) != 0 {
+		t.Fatalf("%d more lines, want none", len(lines))
+	}
+}
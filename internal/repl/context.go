@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"fmt"
+	"goMud/internal/vm"
+	"io"
+)
+
+type Context struct {
+	objects map[string]*vm.ObjectValue
+}
+
+func NewContext() *Context {
+	return &Context{objects: make(map[string]*vm.ObjectValue)}
+}
+
+func (c *Context) GetObjectValueFromContext(name string) *vm.ObjectValue {
+	return c.objects[name]
+}
+
+func (c *Context) Set(name string, object *vm.Object) {
+	c.objects[name] = vm.NewObjectValue(object)
+}
+
+// NewStubContext provides a player whose output goes to out and a room that
+// is either loaded from the mudlib or an empty stand-in when room is "".
+func NewStubContext(out io.Writer, room string) *Context {
+	c := NewContext()
+	c.Set("player", newStubPlayer(out, c))
+	if room != "" {
+		c.Set("room", vm.LoadObject(room))
+	} else {
+		c.Set("room", vm.NewObjectFromClass(*vm.NewEmptyClass("<room>")))
+	}
+	return c
+}
+
+func newStubPlayer(out io.Writer, c *Context) *vm.Object {
+	class := vm.NewEmptyClass("<player>")
+	player := vm.NewObjectFromClass(*class)
+	class.RegisterInternalMethod("Send", 1, 0, func(values []vm.Value) []vm.Value {
+		fmt.Fprintln(out, values[0].String())
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("String", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewStringValue(player.String())}
+	})
+	class.RegisterInternalMethod("SendGMCP", 2, 0, func(values []vm.Value) []vm.Value {
+		fmt.Fprintln(out, "[GMCP "+values[0].String()+"]")
+		return []vm.Value{}
+	})
+	class.RegisterInternalMethod("GMCPSupports", 1, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(0)}
+	})
+	class.RegisterInternalMethod("Width", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(80)}
+	})
+	class.RegisterInternalMethod("Height", 0, 1, func(values []vm.Value) []vm.Value {
+		return []vm.Value{vm.NewNumberValue(24)}
+	})
+	class.RegisterInternalMethod("MoveTo", 1, 0, func(values []vm.Value) []vm.Value {
+		room := values[0].String()
+		fmt.Fprintln(out, "[moved to "+room+"]")
+		destination := vm.LoadObject(room)
+		c.Set("room", destination)
+		if err := vm.GetVirtualMachine().MoveObject(player, destination, c); err != nil {
+			panic(err)
+		}
+		return []vm.Value{}
+	})
+	return player
+}
@@ -0,0 +1,140 @@
+package repl
+
+import (
+	"errors"
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/compiler"
+	"goMud/internal/gmsl/lexer"
+	"goMud/internal/vm"
+	"strconv"
+	"strings"
+)
+
+const functionName = "Eval"
+
+// Session compiles every input as the body of a throwaway function. Variables
+// declared by earlier inputs are declared again at the top of that body in
+// the same order and with the type of the value they hold, so they land in
+// the same registers, which the session carries over between runs.
+type Session struct {
+	machine   *vm.VirtualMachine
+	context   vm.ContextProvider
+	variables []string
+	types     []string
+	registers []vm.Value
+}
+
+func NewSession(machine *vm.VirtualMachine, context vm.ContextProvider) *Session {
+	return &Session{machine: machine, context: context, registers: make([]vm.Value, vm.RegisterCount)}
+}
+
+func (s *Session) Variables() []string {
+	return s.variables
+}
+
+// NeedsMore tells whether the input has unbalanced braces and the caller
+// should keep reading lines before evaluating it.
+func (s *Session) NeedsMore(input string) bool {
+	depth := 0
+	for _, token := range gmsl.Parse("", input).Tokens() {
+		switch token.Typ {
+		case lexer.OpenBraceToken:
+			depth++
+		case lexer.CloseBraceToken:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func (s *Session) source(body string, returns string) (string, int) {
+	var b strings.Builder
+	b.WriteString("package repl\n\nfunc " + functionName + "() " + returns + "{\n")
+	for n, name := range s.variables {
+		b.WriteString("var " + name + " " + s.types[n] + "\n")
+	}
+	offset := 3 + len(s.variables)
+	b.WriteString(body)
+	b.WriteString("\n}\n")
+	return b.String(), offset
+}
+
+func (s *Session) compile(input string) (*gmsl.Result, error) {
+	source, offset := s.source(input, "")
+	result := gmsl.Compile("<input>", source)
+	if !result.HasErrors() {
+		return result, nil
+	}
+
+	// Bare expressions are not statements, evaluate them as a return value.
+	expression, _ := s.source("return "+input, "string ")
+	expressionResult := gmsl.Compile("<input>", expression)
+	if !expressionResult.HasErrors() {
+		return expressionResult, nil
+	}
+
+	d := result.Diagnostics[0]
+	inputLines := strings.Count(strings.TrimRight(input, "\n"), "\n") + 1
+	if d.Line > offset+inputLines {
+		// The statement parser ran past the input, the expression error is
+		// the more useful one.
+		d = expressionResult.Diagnostics[0]
+		if d.Line == offset+1 {
+			d.Column -= len("return ")
+		}
+	}
+	if d.Line > offset {
+		return nil, errors.New(strconv.Itoa(d.Line-offset) + ":" + strconv.Itoa(d.Column) + ": " + d.Message)
+	}
+	return nil, errors.New(d.Message)
+}
+
+func (s *Session) Eval(input string) ([]vm.Value, error) {
+	result, err := s.compile(input)
+	if err != nil {
+		return nil, err
+	}
+	f := result.Assembly.GetFunctions()[0]
+	names := f.GetRegisterNames()
+	if len(names) > len(s.registers) {
+		return nil, errors.New("too many variables, the VM has " + strconv.Itoa(vm.RegisterCount) + " registers")
+	}
+
+	values, err := s.machine.Evaluate(f, s.registers, s.context)
+	if err != nil {
+		return nil, err
+	}
+	s.variables = names
+	s.types = make([]string, len(names))
+	for n, t := range f.GetRegisterTypes() {
+		s.types[n] = typeOf(s.registers[n], t)
+	}
+	return values, nil
+}
+
+// typeOf names the type of a value for its declaration, variables declared
+// with := are strings to the compiler whatever they hold.
+func typeOf(v vm.Value, declared compiler.Type) string {
+	switch v.(type) {
+	case vm.NumberValue, vm.BooleanValue:
+		return compiler.IntType.String()
+	case vm.ObjectValue, *vm.ObjectValue:
+		return compiler.ObjectType.String()
+	case *vm.StringValue, vm.StringValue:
+		return compiler.StringType.String()
+	}
+	return declared.String()
+}
+
+func FormatValue(v vm.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case *vm.StringValue:
+		return strconv.Quote(v.Value)
+	case vm.StringValue:
+		return strconv.Quote(v.Value)
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// CreateHookMethod is called on a new blueprint or clone when its class has
+// it, before anyone else can see the object. It is where objects add their
+// actions. Objects restored from a snapshot do not run it, the snapshot
+// brings back what it set up.
+const CreateHookMethod = "Create"
+
+// action ties a verb to the method of the object that added it.
+type action struct {
+	verb   string
+	method string
+}
+
+// addAction makes verb run method, replacing an earlier action for the verb.
+func (o *Object) addAction(verb string, method string) {
+	verb = strings.ToLower(verb)
+	for i := range o.actions {
+		if o.actions[i].verb == verb {
+			o.actions[i].method = method
+			return
+		}
+	}
+	o.actions = append(o.actions, action{verb: verb, method: method})
+}
+
+func (o *Object) removeAction(verb string) bool {
+	n := len(o.actions)
+	o.actions = slices.DeleteFunc(o.actions, func(a action) bool {
+		return a.verb == strings.ToLower(verb)
+	})
+	return len(o.actions) < n
+}
+
+func (o *Object) findAction(verb string) *action {
+	for i := range o.actions {
+		if o.actions[i].verb == verb {
+			return &o.actions[i]
+		}
+	}
+	return nil
+}
+
+// create runs the Create hook of a new object, one that fails is destructed
+// again.
+func (vm *VirtualMachine) create(o *Object) {
+	if o.class.GetMethod(CreateHookMethod) == nil {
+		return
+	}
+	if _, err := vm.Call(o, CreateHookMethod, nil, vm.defaultContext); err != nil {
+		vm.Destruct(o)
+		log.Panicln("Create failed in", o.name+":", err)
+	}
+}
+
+// commandCandidates lists the objects whose actions a player can use, in the
+// order they are tried like in LPC: what the player carries, the room, the
+// other objects in the room and last the player's own commands in self.
+func commandCandidates(player *Object, self *Object) []*Object {
+	result := player.AllInventory()
+	if room := player.environment; room != nil {
+		result = append(result, room)
+		for _, o := range room.inventory {
+			if o != player {
+				result = append(result, o)
+			}
+		}
+	}
+	return append(result, self)
+}
+
+// Command splits a line typed by player into a verb and the rest and runs
+// the actions added for the verb until one returns true. An action method
+// takes the rest of the line as a string, one without a return value
+// always handles the line. self holds the player's own actions, the player
+// itself when nil. Command tells whether an action handled the line, the
+// caller answers anything else with its "What?".
+func (vm *VirtualMachine) Command(player *Object, self *Object, line string, contextProvider ContextProvider) (bool, error) {
+	verb, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+	verb, args = strings.ToLower(verb), strings.TrimSpace(args)
+	if verb == "" {
+		return false, nil
+	}
+	if self == nil {
+		self = player
+	}
+	for _, o := range commandCandidates(player, self) {
+		a := o.findAction(verb)
+		if a == nil || o.destructed {
+			continue
+		}
+		m := o.method(a.method)
+		if m == nil {
+			logger.Warn("Action without a method", "object", o.name, "verb", verb, "method", a.method)
+			continue
+		}
+		arguments := []Value{}
+		if m.GetArgumentCount() == 1 {
+			arguments = append(arguments, NewStringValue(args))
+		}
+		results, err := vm.Call(o, a.method, arguments, contextProvider)
+		if err != nil {
+			return true, err
+		}
+		if len(results) == 0 || IsTruthy(results[len(results)-1]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
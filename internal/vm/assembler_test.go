@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"goMud/internal/gmsl"
+	"goMud/internal/gmsl/compiler"
+	"testing"
+)
+
+const roundTripSource = `package main
+
+var total int
+
+func Add(a int b int) int {
+    total = total + a + b
+    return total
+}
+
+func Describe(n int) string {
+    label := "small"
+    if n == 3 {
+        label = "three"
+    } else {
+        label = "other"
+    }
+    return label + " " + this.Total()
+}
+
+func Total() int {
+    return this.Add(0 0)
+}
+`
+
+// TestAssembleRoundTrip compiles a class, disassembles it, assembles the
+// listing and runs what came out.
+func TestAssembleRoundTrip(t *testing.T) {
+	result := gmsl.Compile("roundtrip", roundTripSource)
+	if result.HasErrors() {
+		t.Fatal(result.Diagnostics[0])
+	}
+	listing := result.Assembly.String()
+	assembled, err := compiler.Assemble(listing)
+	if err != nil {
+		t.Fatalf("assembling the listing: %v\n%s", err, listing)
+	}
+	if got := assembled.String(); got != listing {
+		t.Fatalf("listing changed by the round trip:\n%s\nwant:\n%s", got, listing)
+	}
+
+	class := &Class{name: "roundtrip", methods: NewMethodsFromAssembly(assembled), variables: assembled.GetVariables()}
+	o := NewObjectFromClass(*class)
+	o.variables = class.newVariables()
+	machine := NewVirtualMachine(t.TempDir())
+
+	for _, c := range []struct {
+		method    string
+		arguments []Value
+		want      string
+	}{
+		{"Add", []Value{NewNumberValue(2), NewNumberValue(3)}, "5"},
+		{"Describe", []Value{NewNumberValue(3)}, "three 5"},
+		{"Describe", []Value{NewNumberValue(4)}, "other 5"},
+	} {
+		results, err := machine.Call(o, c.method, c.arguments, emptyContext{})
+		if err != nil {
+			t.Fatalf("%s: %v", c.method, err)
+		}
+		if len(results) != 1 || results[0].String() != c.want {
+			t.Errorf("%s(%v) = %v, want %s", c.method, c.arguments, results, c.want)
+		}
+	}
+}
@@ -2,37 +2,88 @@ package vm
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"goMud/internal/gmsl"
 	"goMud/internal/gmsl/compiler"
-	"goMud/internal/gmsl/lexer"
-	"goMud/internal/gmsl/parser"
 	"log"
 	"os"
 	"strconv"
 )
 
 type Class struct {
-	name    string
-	methods map[string]Method
+	name      string
+	methods   map[string]Method
+	variables []compiler.Variable
 }
 
 func (c *Class) GetMethod(name string) Method {
 	return c.methods[name]
 }
 
-func newClass(name string) *Class {
-	log.Println("Loading class", name)
+func newClass(mudlibPath string, name string) *Class {
+	logger.Info("Loading class", "class", name)
 
-	b, err := os.ReadFile("mudlib/" + name + ".gms")
+	sourcePath := mudlibPath + "/" + name + compiler.SourceExtension
+	aOut, err := loadBytecode(sourcePath)
 	if err != nil {
-		log.Panicln("Error reading file:", err)
+		logger.Debug("Compiling class", "file", sourcePath, "reason", err)
+		aOut = compileSource(sourcePath)
 	}
 
-	l := lexer.NewLexer(string(b))
-	p := parser.NewParser(l)
-	ast := p.Parse()
-	aOut := compiler.NewCompiler(ast).Compile()
+	methods := NewMethodsFromAssembly(aOut)
+	for _, m := range methods {
+		m.(*vmMethod).file = sourcePath
+	}
+	return &Class{name: name, methods: methods, variables: aOut.GetVariables()}
+}
+
+// newVariables gives an object of the class its variables, numbers and
+// objects start as 0 and strings empty.
+func (c *Class) newVariables() []Value {
+	result := make([]Value, len(c.variables))
+	for n, v := range c.variables {
+		result[n] = zeroValue(v.GetType())
+	}
+	return result
+}
 
-	return &Class{name: name, methods: NewMethodsFromAssembly(aOut)}
+func zeroValue(t compiler.Type) Value {
+	if t == compiler.StringType {
+		return NewStringValue("")
+	}
+	return NewNumberValue(0)
+}
+
+func loadBytecode(sourcePath string) (*compiler.Assembly, error) {
+	bytecodePath := compiler.BytecodePath(sourcePath)
+	bytecodeInfo, err := os.Stat(bytecodePath)
+	if err != nil {
+		return nil, err
+	}
+	if sourceInfo, err := os.Stat(sourcePath); err == nil && !bytecodeInfo.ModTime().After(sourceInfo.ModTime()) {
+		return nil, errors.New(bytecodePath + " is not newer than the source")
+	}
+
+	f, err := os.Open(bytecodePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	aOut, err := compiler.ReadAssembly(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", bytecodePath, err)
+	}
+	return aOut, nil
+}
+
+func compileSource(sourcePath string) *compiler.Assembly {
+	result := gmsl.CompileFile(sourcePath)
+	if result.HasErrors() {
+		log.Panicln("Error compiling class:", result.Diagnostics[0])
+	}
+	return result.Assembly
 }
 
 func NewEmptyClass(name string) *Class {
@@ -40,8 +91,7 @@ func NewEmptyClass(name string) *Class {
 }
 
 func (c *Class) RegisterInternalMethod(name string, argumentCount int, returnValueCount int, handle MethodHandler) {
-	c.methods[name] = &internalMethod{argumentCount, returnValueCount, handle}
-
+	c.methods[name] = &internalMethod{argumentCount: argumentCount, returnValueCount: returnValueCount, handle: handle}
 }
 
 func (c *Class) String() string {
@@ -0,0 +1,308 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"goMud/internal/gmsl/compiler"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	StopBreakpoint = "breakpoint"
+	StopStep       = "step"
+)
+
+var errNotStopped = errors.New("the VM is not stopped")
+
+type Breakpoint struct {
+	Id       int
+	File     string
+	Line     int
+	Function string
+}
+
+func (b Breakpoint) String() string {
+	if b.Function == "" {
+		return strconv.Itoa(b.Id) + ": " + b.File + ":" + strconv.Itoa(b.Line)
+	}
+	if b.File == "" {
+		return strconv.Itoa(b.Id) + ": " + b.Function
+	}
+	return strconv.Itoa(b.Id) + ": " + b.File + " " + b.Function
+}
+
+// ParseBreakpoint reads file:line, Function or class.Function. Files and
+// classes are matched against the end of the source path, so room_a.gms:11
+// and locations/room_a.gms:11 are the same breakpoint.
+func ParseBreakpoint(spec string) (Breakpoint, error) {
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		line, err := strconv.Atoi(spec[i+1:])
+		if err != nil || line < 1 || i == 0 {
+			return Breakpoint{}, errors.New("invalid breakpoint " + spec + ", want file:line")
+		}
+		return Breakpoint{File: withExtension(spec[:i]), Line: line}, nil
+	}
+	if spec == "" {
+		return Breakpoint{}, errors.New("empty breakpoint")
+	}
+	if i := strings.LastIndex(spec, "."); i >= 0 {
+		return Breakpoint{File: withExtension(spec[:i]), Function: spec[i+1:]}, nil
+	}
+	return Breakpoint{Function: spec}, nil
+}
+
+func withExtension(file string) string {
+	if strings.HasSuffix(file, compiler.SourceExtension) {
+		return file
+	}
+	return file + compiler.SourceExtension
+}
+
+func fileMatches(path string, file string) bool {
+	path = filepath.ToSlash(path)
+	return path == file || strings.HasSuffix(path, "/"+file)
+}
+
+func (b Breakpoint) matches(ef *ExecutionFrame, line int) bool {
+	if b.File != "" && !fileMatches(ef.method.file, b.File) {
+		return false
+	}
+	if b.Function != "" {
+		return ef.programCounter == 0 && ef.method.name == b.Function
+	}
+	return line == b.Line
+}
+
+type Variable struct {
+	Name  string
+	Value Value
+}
+
+type StackFrame struct {
+	Function  string
+	File      string
+	Line      int
+	Registers []Variable
+	Stack     []Value
+}
+
+func (f StackFrame) String() string {
+	return f.Function + " at " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// Stop describes where the VM is paused, frames go from the innermost call
+// outwards.
+type Stop struct {
+	Reason     string
+	Breakpoint int
+	Frames     []StackFrame
+}
+
+func (s Stop) String() string {
+	where := "stopped"
+	if len(s.Frames) > 0 {
+		where = "stopped in " + s.Frames[0].String()
+	}
+	if s.Reason == StopBreakpoint {
+		return where + " (breakpoint " + strconv.Itoa(s.Breakpoint) + ")"
+	}
+	return where + " (" + s.Reason + ")"
+}
+
+type stepMode int
+
+const (
+	runFree stepMode = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// Debugger pauses the VM goroutine on breakpoints and steps. While paused
+// nothing else runs on the VM, the client resumes it from its own goroutine
+// with Continue or one of the step methods.
+type Debugger struct {
+	mutex        sync.Mutex
+	breakpoints  []Breakpoint
+	nextId       int
+	mode         stepMode
+	depth        int
+	stopped      *Stop
+	stoppedDepth int
+	resume       chan struct{}
+	onStop       func(Stop)
+}
+
+// NewDebugger creates a debugger that reports every stop to onStop. It is
+// called on the VM goroutine and must not wait for the VM.
+func NewDebugger(onStop func(Stop)) *Debugger {
+	return &Debugger{nextId: 1, resume: make(chan struct{}), onStop: onStop}
+}
+
+// SetDebugger attaches d to the calls started from now on, nil detaches it.
+// Like every other change to the VM it has to happen on the VM goroutine.
+func (vm *VirtualMachine) SetDebugger(d *Debugger) {
+	vm.debugger = d
+}
+
+func (vm *VirtualMachine) GetDebugger() *Debugger {
+	return vm.debugger
+}
+
+func (d *Debugger) SetBreakpoint(spec string) (Breakpoint, error) {
+	b, err := ParseBreakpoint(spec)
+	if err != nil {
+		return b, err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	b.Id = d.nextId
+	d.nextId++
+	d.breakpoints = append(d.breakpoints, b)
+	return b, nil
+}
+
+func (d *Debugger) ClearBreakpoint(id int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for i, b := range d.breakpoints {
+		if b.Id == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Breakpoint(nil), d.breakpoints...)
+}
+
+// Stopped returns where the VM is paused, or nil while it runs.
+func (d *Debugger) Stopped() *Stop {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.stopped
+}
+
+func (d *Debugger) Continue() error {
+	return d.resumeWith(runFree)
+}
+
+// StepIn stops at the next source line, entering called methods.
+func (d *Debugger) StepIn() error {
+	return d.resumeWith(stepIn)
+}
+
+// StepOver stops at the next source line of the current method or its
+// callers.
+func (d *Debugger) StepOver() error {
+	return d.resumeWith(stepOver)
+}
+
+// StepOut stops once the current method has returned to its caller.
+func (d *Debugger) StepOut() error {
+	return d.resumeWith(stepOut)
+}
+
+// Pause stops the VM at the next source line it executes.
+func (d *Debugger) Pause() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped == nil {
+		d.mode = stepIn
+	}
+}
+
+// Close drops every breakpoint and lets a paused VM run on.
+func (d *Debugger) Close() {
+	d.mutex.Lock()
+	d.breakpoints = nil
+	d.mode = runFree
+	stopped := d.stopped != nil
+	d.stopped = nil
+	d.mutex.Unlock()
+	if stopped {
+		d.resume <- struct{}{}
+	}
+}
+
+func (d *Debugger) resumeWith(mode stepMode) error {
+	d.mutex.Lock()
+	if d.stopped == nil {
+		d.mutex.Unlock()
+		return errNotStopped
+	}
+	d.mode = mode
+	d.depth = d.stoppedDepth
+	d.stopped = nil
+	d.mutex.Unlock()
+	d.resume <- struct{}{}
+	return nil
+}
+
+func (d *Debugger) beforeOperation(ef *ExecutionFrame) {
+	if ef.method == nil {
+		return
+	}
+	line := ef.method.lineAt(ef.programCounter)
+	newLine := line != ef.line
+	ef.line = line
+
+	d.mutex.Lock()
+	stop := Stop{}
+	switch {
+	case d.mode == stepIn && newLine,
+		d.mode == stepOver && newLine && ef.depth <= d.depth,
+		d.mode == stepOut && ef.depth < d.depth:
+		stop.Reason = StopStep
+	case newLine:
+		for _, b := range d.breakpoints {
+			if b.matches(ef, line) {
+				stop.Reason, stop.Breakpoint = StopBreakpoint, b.Id
+				break
+			}
+		}
+	}
+	if stop.Reason == "" {
+		d.mutex.Unlock()
+		return
+	}
+	stop.Frames = snapshot(ef)
+	d.stopped, d.stoppedDepth, d.mode = &stop, ef.depth, runFree
+	d.mutex.Unlock()
+
+	d.onStop(stop)
+	<-d.resume
+}
+
+func snapshot(ef *ExecutionFrame) []StackFrame {
+	frames := make([]StackFrame, 0)
+	for f := ef; f != nil && f.method != nil; f = f.parent {
+		frame := StackFrame{
+			Function: f.method.name,
+			File:     f.method.file,
+			Line:     f.method.lineAt(f.programCounter),
+			Stack:    append([]Value(nil), f.valueStack.values[:f.valueStack.pos]...),
+		}
+		for i, r := range f.registers {
+			if i < len(f.method.registerNames) || r != nil {
+				frame.Registers = append(frame.Registers, Variable{f.method.registerName(i), r})
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func (v Variable) String() string {
+	if v.Value == nil {
+		return v.Name + " = nil"
+	}
+	return fmt.Sprintf("%s = %s", v.Name, v.Value)
+}
@@ -0,0 +1,223 @@
+package vm
+
+import (
+	"errors"
+	"goMud/internal/grammar"
+	"goMud/internal/markup"
+	"io/fs"
+	"log"
+	"time"
+)
+
+// DriverObjectName is the context name of the object carrying the driver
+// functions, available to all code whatever the context provider offers.
+const DriverObjectName = "driver"
+
+// ThisObjectName is the context name of the object whose method is running.
+const ThisObjectName = "this"
+
+// FrameMethodHandler is an internal method that needs the calling frame, for
+// the object it runs in or the context it was called with.
+type FrameMethodHandler func(ef *ExecutionFrame, values []Value) []Value
+
+func (c *Class) registerFrameMethod(name string, argumentCount int, returnValueCount int, handle FrameMethodHandler) {
+	c.methods[name] = &internalMethod{argumentCount: argumentCount, returnValueCount: returnValueCount, handleInFrame: handle}
+}
+
+// registerVariadicFrameMethod registers a method taking argumentCount or
+// more arguments.
+func (c *Class) registerVariadicFrameMethod(name string, argumentCount int, returnValueCount int, handle FrameMethodHandler) {
+	c.methods[name] = &internalMethod{argumentCount: argumentCount, returnValueCount: returnValueCount, handleInFrame: handle, variadic: true}
+}
+
+func seconds(v Value) time.Duration {
+	n, ok := v.(NumberValue)
+	if !ok {
+		log.Panicln("Expected a number of seconds, got", v)
+	}
+	return time.Duration(n.Value) * time.Second
+}
+
+func declension(values []Value) *grammar.Declension {
+	d, err := grammar.ParseDeclension(values[0].String(), values[1].String())
+	if err != nil {
+		log.Panicln("Invalid declension:", err)
+	}
+	return d
+}
+
+// grammaticalCase reads a case name like "dop" or "dopełniacz".
+func grammaticalCase(v Value) grammar.Case {
+	c, ok := grammar.ParseCase(v.String())
+	if !ok {
+		log.Panicln("Unknown case", v)
+	}
+	return c
+}
+
+func objectArgument(v Value) *Object {
+	o, ok := v.(ObjectValue)
+	if !ok || o.value == nil {
+		log.Panicln("Expected an object, got", v)
+	}
+	return o.value
+}
+
+// objectOrZero follows LPC, where a missing object is 0.
+func objectOrZero(o *Object) Value {
+	if o == nil {
+		return NewNumberValue(0)
+	}
+	return *NewObjectValue(o)
+}
+
+func (ef *ExecutionFrame) calledFrom() *Object {
+	if ef.self == nil {
+		log.Panicln("Driver function called outside of an object")
+	}
+	return ef.self
+}
+
+func newDriverObject(vm *VirtualMachine) *Object {
+	class := NewEmptyClass("<driver>")
+	class.registerFrameMethod("SetHeartBeat", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		vm.scheduler.SetHeartBeat(ef.calledFrom(), values[0].isTruthy())
+		return []Value{}
+	})
+	class.registerFrameMethod("CallOut", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		vm.scheduler.CallOut(ef.calledFrom(), values[0].String(), seconds(values[1]), nil, ef.contextProvider)
+		return []Value{}
+	})
+	class.registerVariadicFrameMethod("CallOutWith", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		vm.scheduler.CallOut(ef.calledFrom(), values[0].String(), seconds(values[1]), values[2:], ef.contextProvider)
+		return []Value{}
+	})
+	class.registerFrameMethod("RemoveCallOut", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		remaining, ok := vm.scheduler.RemoveCallOut(ef.calledFrom(), values[0].String())
+		if !ok {
+			return []Value{NewNumberValue(-1)}
+		}
+		return []Value{NewNumberValue(int(remaining.Round(time.Second) / time.Second))}
+	})
+	class.registerFrameMethod("AddAction", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().addAction(values[0].String(), values[1].String())
+		return []Value{}
+	})
+	class.registerFrameMethod("RemoveAction", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		if ef.calledFrom().removeAction(values[0].String()) {
+			return []Value{NewNumberValue(1)}
+		}
+		return []Value{NewNumberValue(0)}
+	})
+	class.registerFrameMethod("SetName", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().setName(values[0].String())
+		return []Value{}
+	})
+	class.registerFrameMethod("AddName", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().addName(values[0].String())
+		return []Value{}
+	})
+	class.registerFrameMethod("AddAdjective", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().addAdjective(values[0].String())
+		return []Value{}
+	})
+	class.registerFrameMethod("ShortName", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{NewStringValue(objectArgument(values[0]).ShortName())}
+	})
+	class.registerFrameMethod("SetDeclension", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().setDeclension(declension(values))
+		return []Value{}
+	})
+	class.registerFrameMethod("AddAdjectiveDeclension", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		ef.calledFrom().addAdjectiveDeclension(declension(values))
+		return []Value{}
+	})
+	class.registerFrameMethod("NameCase", 2, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{NewStringValue(objectArgument(values[0]).DeclinedName(grammaticalCase(values[1]), false))}
+	})
+	class.registerFrameMethod("PluralNameCase", 2, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{NewStringValue(objectArgument(values[0]).DeclinedName(grammaticalCase(values[1]), true))}
+	})
+	class.registerFrameMethod("CountedName", 2, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		n, ok := values[1].(NumberValue)
+		if !ok {
+			log.Panicln("CountedName: expected a number, got", values[1])
+		}
+		return []Value{NewStringValue(objectArgument(values[0]).CountedName(n.Value))}
+	})
+	// Wrap breaks text into lines for a window, usually player.Width().
+	class.registerFrameMethod("Wrap", 2, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		width, ok := values[1].(NumberValue)
+		if !ok {
+			log.Panicln("Wrap: expected a number, got", values[1])
+		}
+		return []Value{NewStringValue(markup.Wrap(values[0].String(), width.Value))}
+	})
+	// Match returns an object describing the result, see matchObject.
+	class.registerFrameMethod("Match", 2, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{*NewObjectValue(matchObject(vm.Match(values[0].String(), objectArgument(values[1]))))}
+	})
+	// JSONObject, JSONArray and ParseJSON make the values GMCP messages
+	// carry, see NewJSONObject.
+	class.registerFrameMethod("JSONObject", 0, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		var value any = map[string]any{}
+		return []Value{*NewObjectValue(jsonObject(&value))}
+	})
+	class.registerFrameMethod("JSONArray", 0, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		var value any = &[]any{}
+		return []Value{*NewObjectValue(jsonObject(&value))}
+	})
+	class.registerFrameMethod("ParseJSON", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{*NewObjectValue(parseJSON(values[0].String()))}
+	})
+	class.registerFrameMethod("LoadObject", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{*NewObjectValue(vm.LoadObject(values[0].String()))}
+	})
+	class.registerFrameMethod("CloneObject", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{*NewObjectValue(vm.CloneObject(values[0].String()))}
+	})
+	class.registerFrameMethod("FindObject", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{objectOrZero(vm.FindObject(values[0].String()))}
+	})
+	class.registerFrameMethod("Destruct", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		vm.Destruct(objectArgument(values[0]))
+		return []Value{}
+	})
+	class.registerFrameMethod("ObjectName", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{NewStringValue(objectArgument(values[0]).name)}
+	})
+	class.registerFrameMethod("MoveObject", 2, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		if err := vm.MoveObject(objectArgument(values[0]), objectArgument(values[1]), ef.contextProvider); err != nil {
+			log.Panicln("MoveObject:", err)
+		}
+		return []Value{}
+	})
+	class.registerFrameMethod("Environment", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{objectOrZero(objectArgument(values[0]).environment)}
+	})
+	// There are no arrays, the inventory is walked like in LPC with
+	// FirstInventory and NextInventory.
+	class.registerFrameMethod("FirstInventory", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{objectOrZero(objectArgument(values[0]).firstInventory())}
+	})
+	class.registerFrameMethod("NextInventory", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		return []Value{objectOrZero(objectArgument(values[0]).nextInventory())}
+	})
+	class.registerFrameMethod("SaveObject", 1, 0, func(ef *ExecutionFrame, values []Value) []Value {
+		if err := vm.SaveObject(ef.calledFrom(), values[0].String()); err != nil {
+			log.Panicln("SaveObject:", err)
+		}
+		return []Value{}
+	})
+	class.registerFrameMethod("RestoreObject", 1, 1, func(ef *ExecutionFrame, values []Value) []Value {
+		err := vm.RestoreObject(ef.calledFrom(), values[0].String())
+		if errors.Is(err, fs.ErrNotExist) {
+			return []Value{NewNumberValue(0)}
+		}
+		if err != nil {
+			log.Panicln("RestoreObject:", err)
+		}
+		return []Value{NewNumberValue(1)}
+	})
+	return NewObjectFromClass(*class)
+}
@@ -0,0 +1,164 @@
+package vm
+
+import (
+	"errors"
+	"slices"
+)
+
+const (
+	// EnterHookMethod is called on the destination of a move with the object
+	// that entered it, when the destination's class has it.
+	EnterHookMethod = "ObjectEntered"
+	// LeaveHookMethod is called on the source of a move with the object that
+	// left it, when the source's class has it.
+	LeaveHookMethod = "ObjectLeft"
+	// TellMethod is a method every object has unless its class defines it,
+	// it passes a message to everything inside that can hear.
+	TellMethod = "Tell"
+	// SendMethod is how objects hear, players have it and so can any object
+	// in the mudlib.
+	SendMethod = "Send"
+)
+
+// Environment returns the object this one is in, nil when it is nowhere.
+func (o *Object) Environment() *Object {
+	return o.environment
+}
+
+// AllInventory returns the objects inside this one in the order they
+// arrived.
+func (o *Object) AllInventory() []*Object {
+	return slices.Clone(o.inventory)
+}
+
+// Contains tells whether other is in this object, directly or nested.
+func (o *Object) Contains(other *Object) bool {
+	for e := other.environment; e != nil; e = e.environment {
+		if e == o {
+			return true
+		}
+	}
+	return false
+}
+
+// nextInventory returns the object that arrived in o's environment after o,
+// nil for the last one.
+func (o *Object) nextInventory() *Object {
+	if o.environment == nil {
+		return nil
+	}
+	siblings := o.environment.inventory
+	i := slices.Index(siblings, o)
+	if i < 0 || i+1 == len(siblings) {
+		return nil
+	}
+	return siblings[i+1]
+}
+
+func (o *Object) firstInventory() *Object {
+	if len(o.inventory) == 0 {
+		return nil
+	}
+	return o.inventory[0]
+}
+
+func (o *Object) removeFromEnvironment() {
+	if o.environment != nil {
+		o.environment.inventory = slices.DeleteFunc(o.environment.inventory, func(i *Object) bool {
+			return i == o
+		})
+	}
+	for _, i := range o.inventory {
+		i.environment = o.environment
+		if o.environment != nil {
+			o.environment.inventory = append(o.environment.inventory, i)
+		}
+	}
+	o.inventory = nil
+	o.environment = nil
+}
+
+// moveInto takes o out of its environment and puts it last into
+// destination, without the hooks of MoveObject.
+func (o *Object) moveInto(destination *Object) {
+	if o.environment != nil {
+		o.environment.inventory = slices.DeleteFunc(o.environment.inventory, func(i *Object) bool {
+			return i == o
+		})
+	}
+	o.environment = destination
+	destination.inventory = append(destination.inventory, o)
+}
+
+// MoveObject puts o into destination. The hooks run once the move is done,
+// first the leave hook of the old environment and then the enter hook of the
+// destination, with the given context. A failing hook does not undo the move.
+func (vm *VirtualMachine) MoveObject(o *Object, destination *Object, contextProvider ContextProvider) error {
+	switch {
+	case o.destructed || destination.destructed:
+		return errors.New("cannot move destructed object")
+	case o == destination || o.Contains(destination):
+		return errors.New("cannot move " + o.String() + " into itself")
+	case o.environment == destination:
+		return nil
+	}
+	source := o.environment
+	o.moveInto(destination)
+
+	if source != nil {
+		if err := vm.callHook(source, LeaveHookMethod, o, contextProvider); err != nil {
+			return err
+		}
+	}
+	return vm.callHook(destination, EnterHookMethod, o, contextProvider)
+}
+
+// method finds a method of an object, those of its class first and then
+// the ones every object has.
+func (o *Object) method(name string) Method {
+	if m := o.class.GetMethod(name); m != nil {
+		return m
+	}
+	switch name {
+	case TellMethod:
+		return &internalMethod{argumentCount: 1, variadic: true, handleInFrame: func(ef *ExecutionFrame, values []Value) []Value {
+			var exclude []*Object
+			for _, v := range values[1:] {
+				if e, ok := v.(ObjectValue); ok {
+					exclude = append(exclude, e.value)
+				}
+			}
+			ef.machine.Tell(o, values[0].String(), ef.contextProvider, exclude...)
+			return []Value{}
+		}}
+	}
+	return nil
+}
+
+// HasMethod tells whether an object has a method, of its class or one
+// every object has.
+func (o *Object) HasMethod(name string) bool {
+	return o.method(name) != nil
+}
+
+// Tell sends a message to the objects in o that have a Send method, leaving
+// out those in exclude. One that fails to hear it is logged and skipped, the
+// others still get the message.
+func (vm *VirtualMachine) Tell(o *Object, message string, contextProvider ContextProvider, exclude ...*Object) {
+	for _, i := range o.AllInventory() {
+		if slices.Contains(exclude, i) || i.destructed || i.class.GetMethod(SendMethod) == nil {
+			continue
+		}
+		if _, err := vm.Call(i, SendMethod, []Value{NewStringValue(message)}, contextProvider); err != nil {
+			logger.Warn("Tell failed", "object", i.name, "error", err)
+		}
+	}
+}
+
+func (vm *VirtualMachine) callHook(object *Object, method string, moved *Object, contextProvider ContextProvider) error {
+	if object.destructed || object.class.GetMethod(method) == nil {
+		return nil
+	}
+	_, err := vm.Call(object, method, []Value{*NewObjectValue(moved)}, contextProvider)
+	return err
+}
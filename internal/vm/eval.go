@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"goMud/internal/gmsl/compiler"
+	"strings"
+)
+
+// Evaluate runs a single compiled function outside any class, registers are
+// copied in before and back out after the run so callers can keep locals
+// between evaluations. Whatever is left on the value stack is returned.
+// Like every other execution it must happen on the VM goroutine.
+func (vm *VirtualMachine) Evaluate(f compiler.FunctionInfo, registers []Value, contextProvider ContextProvider) (results []Value, err error) {
+	method := NewMethodFromAssembly(f).(*vmMethod)
+	ef := NewExecutionFrame(contextProvider)
+	ef.debugger = vm.debugger
+	ef.profiler = vm.profiler
+	ef.machine = vm
+	copy(ef.registers, registers)
+	ef.program = method.operations
+	ef.stringPool = method.GetStrings()
+	ef.method = method
+
+	defer func() {
+		copy(registers, ef.registers)
+		if r := recover(); r != nil {
+			err = RuntimeError(r)
+		}
+	}()
+	ef.run()
+
+	results = make([]Value, ef.valueStack.pos)
+	copy(results, ef.valueStack.values[:ef.valueStack.pos])
+	return results, nil
+}
+
+// Call runs a method to completion and returns its results, a panic in the
+// called code comes back as an error. It must happen on the goroutine that
+// owns the machine.
+func (vm *VirtualMachine) Call(object *Object, method string, arguments []Value, contextProvider ContextProvider) (results []Value, err error) {
+	if object.destructed {
+		return nil, errors.New("call to destructed object " + object.name)
+	}
+	m := object.method(method)
+	if m == nil {
+		return nil, errors.New("unknown method " + method + " in " + object.class.name)
+	}
+	if !acceptsArguments(m, len(arguments)) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", method, m.GetArgumentCount(), len(arguments))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = RuntimeError(r)
+		}
+	}()
+	ef := NewExecutionFrame(contextProvider)
+	ef.debugger = vm.debugger
+	ef.profiler = vm.profiler
+	ef.machine = vm
+	for _, arg := range arguments {
+		ef.valueStack.push(arg)
+	}
+	ef.call(*NewObjectValue(object), NewStringValue(method), len(arguments))
+
+	results = make([]Value, ef.valueStack.pos)
+	copy(results, ef.valueStack.values[:ef.valueStack.pos])
+	return results, nil
+}
+
+// RuntimeError turns what a panic in the VM recovered with into an error.
+func RuntimeError(r any) error {
+	switch r := r.(type) {
+	case error:
+		return r
+	case string:
+		return errors.New(strings.TrimSpace(r))
+	default:
+		return fmt.Errorf("%v", r)
+	}
+}
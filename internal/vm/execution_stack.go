@@ -1,6 +1,9 @@
 package vm
 
-import "log"
+import (
+	"goMud/internal/gmsl/compiler"
+	"log"
+)
 
 type RegisterType int
 
@@ -8,6 +11,8 @@ const (
 	StringRegisterType RegisterType = iota
 )
 
+const RegisterCount = compiler.RegisterCount
+
 type ContextProvider interface {
 	GetObjectValueFromContext(name string) *ObjectValue
 }
@@ -20,11 +25,19 @@ type ExecutionFrame struct {
 	program         []Operation
 	stringPool      []string
 	contextProvider ContextProvider
+	method          *vmMethod
+	parent          *ExecutionFrame
+	depth           int
+	line            int
+	debugger        *Debugger
+	profiler        *Profiler
+	machine         *VirtualMachine
+	self            *Object
 }
 
 func NewExecutionFrame(contextProvider ContextProvider) *ExecutionFrame {
 	return &ExecutionFrame{
-		registers:       make([]Value, 20),
+		registers:       make([]Value, RegisterCount),
 		valueStack:      *NewValueStack(),
 		programCounter:  0,
 		contextProvider: contextProvider,
@@ -39,6 +52,12 @@ func (ef *ExecutionFrame) GetFromStringPool(index int) string {
 }
 
 func (ef *ExecutionFrame) GetObjectFromContext(name string) ObjectValue {
+	if name == DriverObjectName && ef.machine != nil {
+		return *NewObjectValue(ef.machine.driver)
+	}
+	if name == ThisObjectName && ef.self != nil {
+		return *NewObjectValue(ef.self)
+	}
 	obj := ef.contextProvider.GetObjectValueFromContext(name)
 	if obj == nil {
 		log.Panicln("Object not found in context")
@@ -46,10 +65,19 @@ func (ef *ExecutionFrame) GetObjectFromContext(name string) ObjectValue {
 	return *obj
 }
 
-func (ef *ExecutionFrame) call(object ObjectValue, method Value) {
-	objectValue := *object.value
-	cls := objectValue.GetClass()
-	m := cls.GetMethod(method.(*StringValue).Value)
+// call runs a method with argumentCount arguments from the stack, -1 takes
+// as many as the method has.
+func (ef *ExecutionFrame) call(object ObjectValue, method Value, argumentCount int) {
+	if object.value.destructed {
+		log.Panicln("Call to destructed object", object.value.name)
+	}
+	name := method.(*StringValue).Value
+	m := object.value.method(name)
+	if m != nil && argumentCount < 0 {
+		argumentCount = m.GetArgumentCount()
+	} else if m != nil && !acceptsArguments(m, argumentCount) {
+		log.Panicln(name, "expects", m.GetArgumentCount(), "arguments, got", argumentCount)
+	}
 	switch m.(type) {
 	case *vmMethod:
 		ef.nextFrame = NewExecutionFrame(ef.contextProvider)
@@ -58,30 +86,56 @@ func (ef *ExecutionFrame) call(object ObjectValue, method Value) {
 		}
 		ef.nextFrame.program = m.(*vmMethod).operations
 		ef.nextFrame.stringPool = m.(*vmMethod).GetStrings()
+		ef.nextFrame.method = m.(*vmMethod)
+		ef.nextFrame.parent = ef
+		ef.nextFrame.depth = ef.depth + 1
+		ef.nextFrame.debugger = ef.debugger
+		ef.nextFrame.profiler = ef.profiler
+		ef.nextFrame.machine = ef.machine
+		ef.nextFrame.self = object.value
 		ef.nextFrame.run()
 		for i := 0; i < m.GetReturnValueCount(); i++ {
 			ef.valueStack.push(ef.nextFrame.valueStack.pop())
 		}
 		ef.nextFrame = nil
 	case *internalMethod:
-		arguments := make([]Value, m.GetArgumentCount())
-		for i := m.GetArgumentCount() - 1; i >= 0; i-- {
+		arguments := make([]Value, argumentCount)
+		for i := argumentCount - 1; i >= 0; i-- {
 			arguments[i] = ef.valueStack.pop()
 		}
-		result := m.(*internalMethod).handle(arguments)
+		var result []Value
+		if m.(*internalMethod).handleInFrame != nil {
+			result = m.(*internalMethod).handleInFrame(ef, arguments)
+		} else {
+			result = m.(*internalMethod).handle(arguments)
+		}
 		for _, r := range result {
 			ef.valueStack.push(r)
 		}
 	}
 }
 
+func (ef *ExecutionFrame) variables() []Value {
+	if ef.self == nil {
+		log.Panicln("Object variable used outside of an object")
+	}
+	return ef.self.variables
+}
+
 func (ef *ExecutionFrame) PopValue() Value {
 	return ef.valueStack.pop()
 }
 
 func (ef *ExecutionFrame) run() {
 	for ef.programCounter < len(ef.program) {
-		ef.program[ef.programCounter].Execute(ef)
+		if ef.debugger != nil {
+			ef.debugger.beforeOperation(ef)
+		}
+		if ef.profiler != nil {
+			ef.profiler.execute(ef)
+		} else {
+			ef.program[ef.programCounter].Execute(ef)
+		}
 		ef.programCounter++
 	}
 }
@@ -0,0 +1,145 @@
+package vm
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+)
+
+// JSONClassName is the class of the objects wrapping JSON values.
+const JSONClassName = "<json>"
+
+// NewJSONObject wraps a JSON value for GMSL, which has no maps or arrays:
+// Get, Set, Append and Count reach into objects and arrays, String gives
+// the JSON text back.
+func NewJSONObject(data json.RawMessage) (*Object, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value = shareArrays(value)
+	return jsonObject(&value), nil
+}
+
+// shareArrays puts the arrays of a decoded value behind pointers. The
+// objects wrapping a nested array then append to the one its parent holds,
+// maps are shared already.
+func shareArrays(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = shareArrays(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = shareArrays(value)
+		}
+		return &v
+	}
+	return v
+}
+
+// JSONData encodes a GMSL value, strings, numbers and booleans as
+// themselves and objects made by NewJSONObject or the driver as their JSON
+// value.
+func JSONData(v Value) (json.RawMessage, error) {
+	return json.Marshal(fromGMSL(v))
+}
+
+func jsonObject(value *any) *Object {
+	class := NewEmptyClass(JSONClassName)
+	o := NewObjectFromClass(*class)
+	o.json = value
+	class.RegisterInternalMethod("Get", 1, 1, func(values []Value) []Value {
+		switch v := (*value).(type) {
+		case map[string]any:
+			return []Value{toGMSL(v[values[0].String()])}
+		case *[]any:
+			if n, ok := values[0].(NumberValue); ok && n.Value >= 0 && n.Value < len(*v) {
+				return []Value{toGMSL((*v)[n.Value])}
+			}
+		}
+		return []Value{NewNumberValue(0)}
+	})
+	class.RegisterInternalMethod("Set", 2, 0, func(values []Value) []Value {
+		m, ok := (*value).(map[string]any)
+		if !ok {
+			log.Panicln("Set: not a JSON object")
+		}
+		m[values[0].String()] = fromGMSL(values[1])
+		return []Value{}
+	})
+	class.RegisterInternalMethod("Append", 1, 0, func(values []Value) []Value {
+		a, ok := (*value).(*[]any)
+		if !ok {
+			log.Panicln("Append: not a JSON array")
+		}
+		*a = append(*a, fromGMSL(values[0]))
+		return []Value{}
+	})
+	class.RegisterInternalMethod("Count", 0, 1, func(values []Value) []Value {
+		switch v := (*value).(type) {
+		case map[string]any:
+			return []Value{NewNumberValue(len(v))}
+		case *[]any:
+			return []Value{NewNumberValue(len(*v))}
+		}
+		return []Value{NewNumberValue(0)}
+	})
+	class.RegisterInternalMethod("String", 0, 1, func(values []Value) []Value {
+		text, err := json.Marshal(*value)
+		if err != nil {
+			log.Panicln("String:", err)
+		}
+		return []Value{NewStringValue(string(text))}
+	})
+	return o
+}
+
+// toGMSL turns a decoded JSON value into a GMSL one. Numbers are truncated
+// to integers, true is 1 and false and null are 0.
+func toGMSL(v any) Value {
+	switch v := v.(type) {
+	case string:
+		return NewStringValue(v)
+	case float64:
+		return NewNumberValue(int(v))
+	case int:
+		return NewNumberValue(v)
+	case bool:
+		if v {
+			return NewNumberValue(1)
+		}
+		return NewNumberValue(0)
+	case map[string]any, *[]any:
+		var value any = v
+		return *NewObjectValue(jsonObject(&value))
+	}
+	return NewNumberValue(0)
+}
+
+func fromGMSL(v Value) any {
+	switch v := v.(type) {
+	case NumberValue:
+		return v.Value
+	case BooleanValue:
+		return v.Value
+	case ObjectValue:
+		if v.value != nil && v.value.json != nil {
+			return *v.value.json
+		}
+		log.Panicln("Expected a JSON value, got", v)
+	case *ObjectValue:
+		return fromGMSL(*v)
+	}
+	return v.String()
+}
+
+// parseJSON is the driver's ParseJSON, it panics on invalid JSON.
+func parseJSON(text string) *Object {
+	o, err := NewJSONObject(json.RawMessage(text))
+	if err != nil {
+		log.Panicln("ParseJSON: "+strconv.Quote(text)+":", err)
+	}
+	return o
+}
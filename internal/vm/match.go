@@ -0,0 +1,255 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"goMud/internal/grammar"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// setName makes name the noun an object is known by, first in its short
+// description.
+func (o *Object) setName(name string) {
+	name = strings.ToLower(name)
+	o.nouns = slices.DeleteFunc(o.nouns, func(n string) bool { return n == name })
+	o.nouns = append([]string{name}, o.nouns...)
+}
+
+func (o *Object) addName(name string) {
+	if name = strings.ToLower(name); !slices.Contains(o.nouns, name) {
+		o.nouns = append(o.nouns, name)
+	}
+}
+
+func (o *Object) addAdjective(adjective string) {
+	if adjective = strings.ToLower(adjective); !slices.Contains(o.adjectives, adjective) {
+		o.adjectives = append(o.adjectives, adjective)
+	}
+}
+
+// ShortName is how messages name an object, its adjectives and name like
+// "red sword", empty for an object without a name.
+func (o *Object) ShortName() string {
+	if len(o.nouns) == 0 {
+		return ""
+	}
+	return strings.Join(append(slices.Clone(o.adjectives), o.nouns[0]), " ")
+}
+
+// setDeclension gives the object a Polish name, its nominative singular
+// becomes the name.
+func (o *Object) setDeclension(d *grammar.Declension) {
+	o.declension = d
+	o.setName(d.Singular[grammar.Nominative])
+}
+
+func (o *Object) addAdjectiveDeclension(d *grammar.Declension) {
+	o.adjectiveDeclensions = append(o.adjectiveDeclensions, d)
+	o.addAdjective(d.Singular[grammar.Nominative])
+}
+
+// DeclinedName names the object in a case and number, adjectives first:
+// "czerwonego miecza". Objects without a declension keep their short name.
+func (o *Object) DeclinedName(c grammar.Case, plural bool) string {
+	if o.declension == nil {
+		return o.ShortName()
+	}
+	words := make([]string, 0, len(o.adjectiveDeclensions)+1)
+	for _, a := range o.adjectiveDeclensions {
+		words = append(words, a.Form(c, plural))
+	}
+	return strings.Join(append(words, o.declension.Form(c, plural)), " ")
+}
+
+// CountedName puts a number in front of the name in the form Polish wants
+// after it, "5 mieczy".
+func (o *Object) CountedName(n int) string {
+	c, plural := grammar.Counted(n)
+	return strconv.Itoa(n) + " " + o.DeclinedName(c, plural)
+}
+
+// matchesNoun tells whether word names the object, and whether it did so in
+// the plural. Any form of a Polish name does.
+func (o *Object) matchesNoun(word string) (matches bool, plural bool) {
+	if slices.Contains(o.nouns, word) {
+		return true, false
+	}
+	if o.declension != nil {
+		if ok, p := o.declension.Matches(word); ok {
+			return true, p
+		}
+	}
+	for _, singular := range grammar.Singulars(word) {
+		if slices.Contains(o.nouns, singular) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (o *Object) matchesAdjectives(adjectives []string) bool {
+	for _, a := range adjectives {
+		if !slices.Contains(o.adjectives, a) && !o.matchesAdjectiveForm(a) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Object) matchesAdjectiveForm(word string) bool {
+	for _, d := range o.adjectiveDeclensions {
+		if ok, _ := d.Matches(word); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match is what Match found for a phrase. Err holds a message for the
+// player when the phrase did not resolve.
+type Match struct {
+	Objects     []*Object
+	Preposition string
+	// Indirect is the object after the preposition, nil without one.
+	Indirect *Object
+	Err      error
+}
+
+// Match resolves a phrase typed by looker against what it carries and,
+// after that, what is around it. A direct object after "from", as in "take
+// sword from chest", is looked for inside the indirect object.
+func (vm *VirtualMachine) Match(text string, looker *Object) *Match {
+	phrase, err := grammar.Parse(text)
+	if err != nil {
+		return &Match{Err: errors.New("What do you mean?")}
+	}
+	result := &Match{Preposition: phrase.Preposition}
+	candidates := inReach(looker)
+	if phrase.Indirect != nil {
+		indirect, err := resolve(*phrase.Indirect, candidates)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if len(indirect) > 1 {
+			result.Err = errors.New("You can only do that with one thing at a time.")
+			return result
+		}
+		result.Indirect = indirect[0]
+		if phrase.Preposition == "from" {
+			candidates = result.Indirect.AllInventory()
+		}
+	}
+	result.Objects, result.Err = resolve(phrase.Direct, candidates)
+	return result
+}
+
+// inReach lists what looker carries and then what is in its environment.
+func inReach(looker *Object) []*Object {
+	result := looker.AllInventory()
+	if looker.environment != nil {
+		for _, o := range looker.environment.inventory {
+			if o != looker {
+				result = append(result, o)
+			}
+		}
+	}
+	return result
+}
+
+func resolve(np grammar.NounPhrase, candidates []*Object) ([]*Object, error) {
+	matches := make([]*Object, 0)
+	plural := false
+	for _, o := range candidates {
+		if o.destructed || len(o.nouns) == 0 {
+			continue
+		}
+		if np.Noun == "" {
+			matches = append(matches, o)
+			continue
+		}
+		ok, p := o.matchesNoun(np.Noun)
+		if ok && o.matchesAdjectives(np.Adjectives) {
+			matches = append(matches, o)
+			plural = plural || p
+		}
+	}
+	if len(matches) == 0 {
+		if np.Noun == "" {
+			return nil, errors.New("You see nothing here.")
+		}
+		return nil, fmt.Errorf("You see no %s here.", np)
+	}
+
+	switch {
+	case np.All || (plural && np.Quantity == 0 && np.Ordinal == 0):
+		return matches, nil
+	case np.Quantity > 0:
+		if np.Quantity > len(matches) {
+			return nil, fmt.Errorf("There are only %d of those here.", len(matches))
+		}
+		if np.Quantity > 1 || len(matches) == 1 {
+			return matches[:np.Quantity], nil
+		}
+	case np.Ordinal > 0:
+		if np.Ordinal > len(matches) {
+			return nil, fmt.Errorf("There are only %d of those here.", len(matches))
+		}
+		return matches[np.Ordinal-1 : np.Ordinal], nil
+	}
+	return unambiguous(matches)
+}
+
+// unambiguous picks the single object a phrase meant. Objects that look the
+// same are interchangeable and the first one is taken, otherwise the player
+// is asked which.
+func unambiguous(matches []*Object) ([]*Object, error) {
+	names := make([]string, 0)
+	for _, o := range matches {
+		if !slices.Contains(names, o.ShortName()) {
+			names = append(names, o.ShortName())
+		}
+	}
+	if len(names) == 1 {
+		return matches[:1], nil
+	}
+	for i := range names {
+		names[i] = "the " + names[i]
+	}
+	last := len(names) - 1
+	return nil, fmt.Errorf("Which do you mean, %s or %s?", strings.Join(names[:last], ", "), names[last])
+}
+
+// matchObject wraps a Match for GMSL, which has no arrays: Count and Get
+// walk the objects.
+func matchObject(m *Match) *Object {
+	class := NewEmptyClass("<match>")
+	class.RegisterInternalMethod("Error", 0, 1, func(values []Value) []Value {
+		if m.Err == nil {
+			return []Value{NewStringValue("")}
+		}
+		return []Value{NewStringValue(m.Err.Error())}
+	})
+	class.RegisterInternalMethod("Count", 0, 1, func(values []Value) []Value {
+		return []Value{NewNumberValue(len(m.Objects))}
+	})
+	class.RegisterInternalMethod("Get", 1, 1, func(values []Value) []Value {
+		n, ok := values[0].(NumberValue)
+		if !ok || n.Value < 0 || n.Value >= len(m.Objects) {
+			return []Value{NewNumberValue(0)}
+		}
+		return []Value{*NewObjectValue(m.Objects[n.Value])}
+	})
+	class.RegisterInternalMethod("Preposition", 0, 1, func(values []Value) []Value {
+		return []Value{NewStringValue(m.Preposition)}
+	})
+	class.RegisterInternalMethod("Indirect", 0, 1, func(values []Value) []Value {
+		return []Value{objectOrZero(m.Indirect)}
+	})
+	class.RegisterInternalMethod("String", 0, 1, func(values []Value) []Value {
+		return []Value{NewStringValue("match of " + strconv.Itoa(len(m.Objects)))}
+	})
+	return NewObjectFromClass(*class)
+}
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"goMud/internal/gmsl/compiler"
+	"strconv"
 )
 
 type Method interface {
@@ -10,10 +11,14 @@ type Method interface {
 }
 
 type vmMethod struct {
+	name             string
+	file             string
 	argumentCount    int
 	returnValueCount int
 	operations       []Operation
 	strings          []string
+	lines            []int
+	registerNames    []string
 }
 
 type MethodHandler func([]Value) []Value
@@ -21,7 +26,10 @@ type MethodHandler func([]Value) []Value
 type internalMethod struct {
 	argumentCount    int
 	returnValueCount int
-	handle           MethodHandler
+	// variadic methods take argumentCount or more arguments.
+	variadic      bool
+	handle        MethodHandler
+	handleInFrame FrameMethodHandler
 }
 
 func (m *internalMethod) GetArgumentCount() int {
@@ -32,6 +40,14 @@ func (m *internalMethod) GetReturnValueCount() int {
 	return m.returnValueCount
 }
 
+// acceptsArguments tells whether a method can be called with n arguments.
+func acceptsArguments(m Method, n int) bool {
+	if im, ok := m.(*internalMethod); ok && im.variadic {
+		return n >= im.argumentCount
+	}
+	return n == m.GetArgumentCount()
+}
+
 func (m *vmMethod) GetArgumentCount() int {
 	return m.argumentCount
 }
@@ -53,7 +69,7 @@ func NewMethodsFromAssembly(aOut *compiler.Assembly) map[string]Method {
 }
 
 func NewMethodFromAssembly(f compiler.FunctionInfo) Method {
-	result := &vmMethod{argumentCount: f.GetArgumentCount(), returnValueCount: f.GetReturnValueCount(), strings: f.GetStrings(), operations: make([]Operation, 0)}
+	result := &vmMethod{name: f.GetName(), argumentCount: f.GetArgumentCount(), returnValueCount: f.GetReturnValueCount(), strings: f.GetStrings(), operations: make([]Operation, 0), registerNames: f.GetRegisterNames()}
 	labelPos := make(map[string]int)
 	posRequestingLabel := make(map[int]string)
 
@@ -77,7 +93,11 @@ func NewMethodFromAssembly(f compiler.FunctionInfo) Method {
 		case compiler.OpCmp:
 			result.addOperation(&EqualOperation{})
 		case compiler.OpCall:
-			result.addOperation(&MethodCallOperation{})
+			argumentCount := -1
+			if e.HasArgument() {
+				argumentCount = e.GetArgument()
+			}
+			result.addOperation(&MethodCallOperation{argumentCount: argumentCount})
 		case compiler.OpPushContext:
 			result.addOperation(&PushContextOperation{contextNameIndex: e.GetArgument()})
 		case compiler.OpPopToRegister:
@@ -94,9 +114,17 @@ func NewMethodFromAssembly(f compiler.FunctionInfo) Method {
 			result.addOperation(&PushStringOperation{index: e.GetArgument()})
 		case compiler.OpPushNumber:
 			result.addOperation(&PushNumberOperation{value: e.GetArgument()})
+		case compiler.OpPushVariable:
+			result.addOperation(&PushVariableOperation{index: e.GetArgument()})
+		case compiler.OpPopToVariable:
+			result.addOperation(&PopToVariableOperation{index: e.GetArgument()})
 		case compiler.OpNoOp:
 			// Do nothing
 		}
+		source := e.GetSource()
+		for len(result.lines) < len(result.operations) {
+			result.lines = append(result.lines, source.GetPosition().Line)
+		}
 	}
 
 	for pos, label := range posRequestingLabel {
@@ -112,6 +140,22 @@ func NewMethodFromAssembly(f compiler.FunctionInfo) Method {
 	return result
 }
 
+// lineAt maps an operation back to the source line it was compiled from, 0
+// when the method has no positions.
+func (m *vmMethod) lineAt(pc int) int {
+	if pc < 0 || pc >= len(m.lines) {
+		return 0
+	}
+	return m.lines[pc]
+}
+
+func (m *vmMethod) registerName(index int) string {
+	if index < len(m.registerNames) && m.registerNames[index] != "" {
+		return m.registerNames[index]
+	}
+	return "r" + strconv.Itoa(index)
+}
+
 func (m *vmMethod) GetStrings() []string {
 	return m.strings
 }
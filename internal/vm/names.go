@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"errors"
+	"goMud/internal/grammar"
+	"strconv"
+	"strings"
+)
+
+// Keywords of the lines that keep what an object is called in snapshots and
+// save files. Mudlib code can change names at any time, like a sword that is
+// painted red, so they are kept with the variables.
+const (
+	nameKeyword                = "name"
+	adjectiveKeyword           = "adjective"
+	declensionKeyword          = "declension"
+	adjectiveDeclensionKeyword = "adjective-declension"
+)
+
+// objectNames collects the name lines read for one object.
+type objectNames struct {
+	found                bool
+	nouns                []string
+	adjectives           []string
+	declension           *grammar.Declension
+	adjectiveDeclensions []*grammar.Declension
+}
+
+// writeNames adds the name lines of o to b, each keyword after prefix.
+func writeNames(b *strings.Builder, o *Object, prefix string) {
+	for _, n := range o.nouns {
+		b.WriteString(prefix + nameKeyword + " " + strconv.Quote(n) + "\n")
+	}
+	for _, a := range o.adjectives {
+		b.WriteString(prefix + adjectiveKeyword + " " + strconv.Quote(a) + "\n")
+	}
+	if o.declension != nil {
+		writeDeclension(b, prefix+declensionKeyword, o.declension)
+	}
+	for _, d := range o.adjectiveDeclensions {
+		writeDeclension(b, prefix+adjectiveDeclensionKeyword, d)
+	}
+}
+
+func writeDeclension(b *strings.Builder, keyword string, d *grammar.Declension) {
+	singular, plural := d.Forms()
+	b.WriteString(keyword + " " + strconv.Quote(singular) + " " + strconv.Quote(plural) + "\n")
+}
+
+// parse reads a name line, ok is false for a keyword that is not one.
+func (n *objectNames) parse(keyword string, rest string) (ok bool, err error) {
+	switch keyword {
+	case nameKeyword, adjectiveKeyword:
+		word, err := strconv.Unquote(rest)
+		if err != nil {
+			return true, err
+		}
+		if keyword == nameKeyword {
+			n.nouns = append(n.nouns, word)
+		} else {
+			n.adjectives = append(n.adjectives, word)
+		}
+	case declensionKeyword, adjectiveDeclensionKeyword:
+		singular, plural, err := splitTwoQuoted(rest)
+		if err != nil {
+			return true, err
+		}
+		d, err := grammar.ParseDeclension(singular, plural)
+		if err != nil {
+			return true, err
+		}
+		if keyword == declensionKeyword {
+			n.declension = d
+		} else {
+			n.adjectiveDeclensions = append(n.adjectiveDeclensions, d)
+		}
+	default:
+		return false, nil
+	}
+	n.found = true
+	return true, nil
+}
+
+// apply gives o the names read, when there were any, replacing those its
+// Create gave it.
+func (n *objectNames) apply(o *Object) {
+	if !n.found {
+		return
+	}
+	o.nouns = n.nouns
+	o.adjectives = n.adjectives
+	o.declension = n.declension
+	o.adjectiveDeclensions = n.adjectiveDeclensions
+}
+
+// splitTwoQuoted reads two quoted strings separated by a space.
+func splitTwoQuoted(s string) (string, string, error) {
+	first, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", err
+	}
+	a, err := strconv.Unquote(first)
+	if err != nil {
+		return "", "", err
+	}
+	rest := strings.TrimSpace(s[len(first):])
+	if rest == "" {
+		return "", "", errors.New("missing second quoted string")
+	}
+	b, err := strconv.Unquote(rest)
+	return a, b, err
+}
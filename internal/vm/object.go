@@ -1,28 +1,150 @@
 package vm
 
-import "bytes"
+import (
+	"bytes"
+	"goMud/internal/grammar"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
 
+const cloneSeparator = "#"
+
+// Object is either the blueprint of a mudlib class, named after its path, a
+// clone of it named path#id, or an internal object created from Go, which
+// has no name and is not in the object table.
 type Object struct {
-	class Class
+	class       Class
+	name        string
+	id          int
+	destructed  bool
+	environment *Object
+	inventory   []*Object
+	variables   []Value
+	actions     []action
+	nouns       []string
+	adjectives  []string
+	// declension has the Polish forms of the name, adjectiveDeclensions
+	// those of the adjectives in order.
+	declension           *grammar.Declension
+	adjectiveDeclensions []*grammar.Declension
+	// json is the value of a <json> object, see NewJSONObject.
+	json *any
 }
 
 func (o *Object) GetClass() Class {
 	return o.class
 }
 
+func (o *Object) GetName() string {
+	return o.name
+}
+
+func (o *Object) GetId() int {
+	return o.id
+}
+
+func (o *Object) IsClone() bool {
+	return strings.Contains(o.name, cloneSeparator)
+}
+
+func (o *Object) IsDestructed() bool {
+	return o.destructed
+}
+
 func (o *Object) String() string {
 	buff := bytes.Buffer{}
 	buff.WriteString("Object[")
+	if o.name != "" {
+		buff.WriteString(o.name)
+		buff.WriteString(", ")
+	}
 	buff.WriteString(o.class.String())
 	buff.WriteString("]")
 	return buff.String()
 }
 
-func NewObject(name string) *Object {
-	class := instance.getClass(name)
-	return &Object{class}
+// LoadObject returns the blueprint of a class in the shared machine.
+func LoadObject(name string) *Object {
+	return GetVirtualMachine().LoadObject(name)
 }
 
 func NewObjectFromClass(class Class) *Object {
-	return &Object{class}
+	return &Object{class: class}
+}
+
+func normalizeObjectName(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+// LoadObject returns the blueprint of a class, loading the class and
+// registering the blueprint when it is not in the object table yet.
+func (vm *VirtualMachine) LoadObject(name string) *Object {
+	name = normalizeObjectName(name)
+	if strings.Contains(name, cloneSeparator) {
+		log.Panicln("Cannot load a clone:", name)
+	}
+	if o, ok := vm.objects[name]; ok {
+		return o
+	}
+	class := vm.getClass(name)
+	o := vm.register(&Object{class: class, name: name, variables: class.newVariables()})
+	vm.create(o)
+	return o
+}
+
+// CloneObject creates a new object of a class, named after the class path
+// and the object's id.
+func (vm *VirtualMachine) CloneObject(name string) *Object {
+	blueprint := vm.LoadObject(name)
+	o := vm.register(&Object{class: blueprint.class, variables: blueprint.class.newVariables()})
+	o.name = blueprint.name + cloneSeparator + strconv.Itoa(o.id)
+	vm.objects[o.name] = o
+	vm.create(o)
+	return o
+}
+
+func (vm *VirtualMachine) register(o *Object) *Object {
+	vm.lastObjectId++
+	o.id = vm.lastObjectId
+	if o.name != "" {
+		vm.objects[o.name] = o
+	}
+	return o
+}
+
+// FindObject looks up a blueprint or a clone by name without loading
+// anything, nil when there is no such object.
+func (vm *VirtualMachine) FindObject(name string) *Object {
+	return vm.objects[normalizeObjectName(name)]
+}
+
+// Destruct removes an object from the object table and its environment and
+// cancels its heartbeat and delayed calls. Every reference to it turns false
+// and calls to it fail. Its inventory falls into its environment.
+// Destructing a blueprint leaves its clones alone, the next load creates a
+// fresh blueprint.
+func (vm *VirtualMachine) Destruct(o *Object) {
+	if o.destructed {
+		return
+	}
+	o.destructed = true
+	if vm.objects[o.name] == o {
+		delete(vm.objects, o.name)
+	}
+	vm.scheduler.removeObject(o)
+	o.removeFromEnvironment()
+}
+
+// Objects lists the object table in the order the objects were created.
+func (vm *VirtualMachine) Objects() []*Object {
+	result := make([]*Object, 0, len(vm.objects))
+	for _, o := range vm.objects {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].id < result[b].id
+	})
+	return result
 }
@@ -16,7 +16,9 @@ type PopToRegisterOperation struct {
 }
 
 func (o *PopToRegisterOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Popping to register", o.index)
+	if tracing() {
+		trace("Popping to register", "register", o.index)
+	}
 	ef.registers[o.index] = ef.valueStack.pop()
 }
 
@@ -29,22 +31,31 @@ type PushContextOperation struct {
 }
 
 func (o *PushContextOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Pushing context", ef.GetFromStringPool(o.contextNameIndex))
+	if tracing() {
+		trace("Pushing context", "name", ef.GetFromStringPool(o.contextNameIndex))
+	}
 	contextName := ef.GetFromStringPool(o.contextNameIndex)
 	context := ef.GetObjectFromContext(contextName)
 	ef.valueStack.push(context)
-	log.Println("Pushed context", contextName)
+	if tracing() {
+		trace("Pushed context", "name", contextName)
+	}
 }
 
 func (o *PushContextOperation) String() string {
 	return "CPUSH " + strconv.Itoa(o.contextNameIndex)
 }
 
+// MethodCallOperation calls a method with argumentCount arguments, -1 for
+// as many as the method has.
 type MethodCallOperation struct {
+	argumentCount int
 }
 
 func (o *MethodCallOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Calling")
+	if tracing() {
+		trace("Calling")
+	}
 	var object = ef.valueStack.pop()
 
 	objectValue, ok := object.(ObjectValue)
@@ -58,25 +69,34 @@ func (o *MethodCallOperation) Execute(ef *ExecutionFrame) {
 		log.Panicln("Value is not a method")
 	}
 
-	ef.call(objectValue, method)
-	log.Println("Called", object, method)
+	ef.call(objectValue, method, o.argumentCount)
+	if tracing() {
+		trace("Called", "object", object, "method", method)
+	}
 }
 
 func (o *MethodCallOperation) String() string {
-	return "CALL"
+	if o.argumentCount < 0 {
+		return "CALL"
+	}
+	return "CALL " + strconv.Itoa(o.argumentCount)
 }
 
 type AddOperation struct{}
 
 func (o *AddOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Adding")
+	if tracing() {
+		trace("Adding")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := b.Add(a)
 
 	ef.valueStack.push(c)
-	log.Println("Added", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Added", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 }
 
 func (o *AddOperation) String() string {
@@ -90,14 +110,18 @@ func (o *SubOperation) String() string {
 }
 
 func (o *SubOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Subtracting")
+	if tracing() {
+		trace("Subtracting")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := b.Subtract(a)
 
 	ef.valueStack.push(c)
-	log.Println("Subtracted", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Subtracted", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 
 }
 
@@ -108,14 +132,18 @@ func (o *MulOperation) String() string {
 }
 
 func (o *MulOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Multiplying")
+	if tracing() {
+		trace("Multiplying")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := b.Multiply(a)
 
 	ef.valueStack.push(c)
-	log.Println("Multiplied", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Multiplied", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 }
 
 type DivOperation struct{}
@@ -125,27 +153,35 @@ func (o *DivOperation) String() string {
 }
 
 func (o *DivOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Dividing")
+	if tracing() {
+		trace("Dividing")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := b.Divide(a)
 
 	ef.valueStack.push(c)
-	log.Println("Divided", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Divided", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 }
 
 type ModOperation struct{}
 
 func (m ModOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Modding")
+	if tracing() {
+		trace("Modding")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := b.Modulo(a)
 
 	ef.valueStack.push(c)
-	log.Println("Modded", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Modded", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 }
 
 func (m ModOperation) String() string {
@@ -157,9 +193,13 @@ type PushStringOperation struct {
 }
 
 func (o *PushStringOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Pushing string", ef.GetFromStringPool(o.index))
+	if tracing() {
+		trace("Pushing string", "value", ef.GetFromStringPool(o.index))
+	}
 	ef.valueStack.push(NewStringValue(ef.GetFromStringPool(o.index)))
-	log.Println("Pushed string", ef.GetFromStringPool(o.index))
+	if tracing() {
+		trace("Pushed string", "value", ef.GetFromStringPool(o.index))
+	}
 }
 
 func (o *PushStringOperation) String() string {
@@ -171,12 +211,16 @@ type JumpIfFalseOperation struct {
 }
 
 func (o *JumpIfFalseOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Jumping if false")
+	if tracing() {
+		trace("Jumping if false")
+	}
 	var a = ef.valueStack.pop()
 	if !a.isTruthy() {
 		ef.programCounter = o.target - 1
 	}
-	log.Println("Jumped if false", a)
+	if tracing() {
+		trace("Jumped if false", "value", a)
+	}
 }
 
 func (o *JumpIfFalseOperation) String() string {
@@ -188,9 +232,13 @@ type JumpOperation struct {
 }
 
 func (o *JumpOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Jumping")
+	if tracing() {
+		trace("Jumping")
+	}
 	ef.programCounter = o.target - 1
-	log.Println("Jumped")
+	if tracing() {
+		trace("Jumped")
+	}
 }
 
 func (o *JumpOperation) String() string {
@@ -200,13 +248,17 @@ func (o *JumpOperation) String() string {
 type EqualOperation struct{}
 
 func (o *EqualOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Comparing")
+	if tracing() {
+		trace("Comparing")
+	}
 	var a = ef.valueStack.pop()
 	var b = ef.valueStack.pop()
 	c := a.equalValue(b)
 	ef.valueStack.push(c)
-	log.Println("Compared", a, b)
-	log.Println("Result", c)
+	if tracing() {
+		trace("Compared", "a", a, "b", b)
+		trace("Result", "value", c)
+	}
 }
 
 func (o *EqualOperation) String() string {
@@ -219,21 +271,59 @@ type PushFromRegisterOperation struct {
 }
 
 func (o *PushFromRegisterOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Pushing from register", o.index)
+	if tracing() {
+		trace("Pushing from register", "register", o.index)
+	}
 	ef.valueStack.push(ef.registers[o.index])
-	log.Println("Pushed from register", o.index)
+	if tracing() {
+		trace("Pushed from register", "register", o.index)
+	}
 }
 
 func (o *PushFromRegisterOperation) String() string {
-	return "RPUSH " + strconv.Itoa(int(o.registerType)) + strconv.Itoa(o.index)
+	return "RPUSH " + strconv.Itoa(int(o.registerType)) + " " + strconv.Itoa(o.index)
+}
+
+type PushVariableOperation struct {
+	index int
+}
+
+func (o *PushVariableOperation) Execute(ef *ExecutionFrame) {
+	if tracing() {
+		trace("Pushing from variable", "variable", o.index)
+	}
+	ef.valueStack.push(ef.variables()[o.index])
+}
+
+func (o *PushVariableOperation) String() string {
+	return "PUVA " + strconv.Itoa(o.index)
+}
+
+type PopToVariableOperation struct {
+	index int
+}
+
+func (o *PopToVariableOperation) Execute(ef *ExecutionFrame) {
+	if tracing() {
+		trace("Popping to variable", "variable", o.index)
+	}
+	ef.variables()[o.index] = ef.valueStack.pop()
+}
+
+func (o *PopToVariableOperation) String() string {
+	return "POVA " + strconv.Itoa(o.index)
 }
 
 type ReturnOperation struct{}
 
 func (o *ReturnOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Returning")
+	if tracing() {
+		trace("Returning")
+	}
 	ef.programCounter = len(ef.program)
-	log.Println("Returned")
+	if tracing() {
+		trace("Returned")
+	}
 }
 
 func (o *ReturnOperation) String() string {
@@ -249,7 +339,11 @@ func (o *PushNumberOperation) String() string {
 }
 
 func (o *PushNumberOperation) Execute(ef *ExecutionFrame) {
-	log.Println("Pushing number", o.value)
+	if tracing() {
+		trace("Pushing number", "value", o.value)
+	}
 	ef.valueStack.push(NewNumberValue(o.value))
-	log.Println("Pushed number", o.value)
+	if tracing() {
+		trace("Pushed number", "value", o.value)
+	}
 }
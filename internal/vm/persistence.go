@@ -0,0 +1,265 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goMud/internal/fsutil"
+	"goMud/internal/gmsl/compiler"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultDataPath = "data"
+	SaveExtension   = ".o"
+	// SaveFormatVersion is written in the header of every save file, files
+	// of a newer version are refused.
+	SaveFormatVersion = 2
+	saveHeader        = "# gmsl save "
+	saveClassHeader   = "# class "
+	objectPrefix      = "object "
+	jsonPrefix        = "json "
+	// namePrefix starts the name lines of a save file, variable names can
+	// not start with it.
+	namePrefix = "@"
+)
+
+// SetDataPath sets the directory save files are kept in.
+func (vm *VirtualMachine) SetDataPath(path string) {
+	vm.dataPath = path
+}
+
+func (vm *VirtualMachine) DataPath() string {
+	return vm.dataPath
+}
+
+// savePath maps the name of a save file to a file in the data directory, the
+// name can not climb out of it.
+func (vm *VirtualMachine) savePath(name string) string {
+	name = filepath.Clean("/" + filepath.FromSlash(name))
+	if !strings.HasSuffix(name, SaveExtension) {
+		name += SaveExtension
+	}
+	return filepath.Join(vm.dataPath, name)
+}
+
+// SaveObject writes the variables of an object to a save file, one variable
+// per line sorted by name so that saves diff well, and after them the names
+// of the object:
+//
+//	# gmsl save 2
+//	# class obj/sword
+//	colour "red"
+//	owner object "obj/coin#4"
+//	@name "sword"
+//	@name "blade"
+//	@adjective "red"
+//
+// JSON values made by the driver are saved as their JSON text after "json ".
+// Other internal objects, like the results of Match, can not be saved. The
+// file is replaced atomically, a crash leaves either the old or the new
+// save behind.
+func (vm *VirtualMachine) SaveObject(o *Object, name string) error {
+	var b strings.Builder
+	b.WriteString(saveHeader + strconv.Itoa(SaveFormatVersion) + "\n")
+	b.WriteString(saveClassHeader + o.class.name + "\n")
+	order := make([]int, len(o.class.variables))
+	for n := range order {
+		order[n] = n
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return o.class.variables[order[a]].GetName() < o.class.variables[order[b]].GetName()
+	})
+	for _, n := range order {
+		value, err := encodeValue(o.variables[n])
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", o.class.variables[n].GetName(), err)
+		}
+		b.WriteString(o.class.variables[n].GetName() + " " + value + "\n")
+	}
+	writeNames(&b, o, namePrefix)
+	return fsutil.WriteFileAtomic(vm.savePath(name), []byte(b.String()), 0o755)
+}
+
+func encodeValue(v Value) (string, error) {
+	switch v := v.(type) {
+	case NumberValue:
+		return strconv.Itoa(v.Value), nil
+	case BooleanValue:
+		return strconv.FormatBool(v.Value), nil
+	case *StringValue:
+		return strconv.Quote(v.Value), nil
+	case StringValue:
+		return strconv.Quote(v.Value), nil
+	case ObjectValue:
+		if v.value == nil || v.value.destructed {
+			return "0", nil
+		}
+		if v.value.json != nil {
+			data, err := JSONData(v)
+			if err != nil {
+				return "", err
+			}
+			return jsonPrefix + string(data), nil
+		}
+		if v.value.name == "" {
+			return "", errors.New("internal object of class " + v.value.class.name + " can not be saved")
+		}
+		return objectPrefix + strconv.Quote(v.value.name), nil
+	case nil:
+		return "0", nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// RestoreObject sets the variables of an object from a save file. Variables
+// the file does not mention keep their value and those the class no longer
+// has are skipped, so classes can gain and lose variables between saves.
+// Saved names replace those of the object. A missing file is an
+// fs.ErrNotExist error.
+func (vm *VirtualMachine) RestoreObject(o *Object, name string) error {
+	path := vm.savePath(name)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	values := make(map[string]Value)
+	var names objectNames
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			if err := checkSaveHeader(text); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		variable, encoded, _ := strings.Cut(text, " ")
+		if keyword, found := strings.CutPrefix(variable, namePrefix); found {
+			ok, err := names.parse(keyword, encoded)
+			if !ok {
+				err = errors.New("unknown entry " + variable)
+			}
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			continue
+		}
+		value, err := vm.decodeValue(encoded)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		values[variable] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	setVariables(o, values, path)
+	names.apply(o)
+	return nil
+}
+
+// setVariables sets the variables of o found in values, file names where
+// they come from in the log.
+func setVariables(o *Object, values map[string]Value, file string) {
+	for n, v := range o.class.variables {
+		value, ok := values[v.GetName()]
+		if !ok {
+			continue
+		}
+		delete(values, v.GetName())
+		if !fitsType(value, v.GetType()) {
+			logger.Warn("Saved value does not fit the variable", "file", file, "object", o.name, "variable", v.GetName(), "value", value)
+			continue
+		}
+		o.variables[n] = value
+	}
+	for variable := range values {
+		logger.Debug("Skipping saved variable the class does not have", "file", file, "object", o.name, "variable", variable)
+	}
+}
+
+func checkSaveHeader(line string) error {
+	if !strings.HasPrefix(line, saveHeader) {
+		return errors.New("not a save file")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(line, saveHeader))
+	if err != nil {
+		return errors.New("invalid save file version")
+	}
+	if version > SaveFormatVersion {
+		return fmt.Errorf("save file version %d is newer than %d", version, SaveFormatVersion)
+	}
+	return nil
+}
+
+func (vm *VirtualMachine) decodeValue(encoded string) (Value, error) {
+	switch {
+	case strings.HasPrefix(encoded, "\""):
+		s, err := strconv.Unquote(encoded)
+		if err != nil {
+			return nil, err
+		}
+		return NewStringValue(s), nil
+	case strings.HasPrefix(encoded, objectPrefix):
+		name, err := strconv.Unquote(strings.TrimPrefix(encoded, objectPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return objectOrZero(vm.restoredObject(name)), nil
+	case strings.HasPrefix(encoded, jsonPrefix):
+		o, err := NewJSONObject(json.RawMessage(strings.TrimPrefix(encoded, jsonPrefix)))
+		if err != nil {
+			return nil, err
+		}
+		return *NewObjectValue(o), nil
+	case encoded == "true" || encoded == "false":
+		return BooleanValue{Value: encoded == "true"}, nil
+	}
+	n, err := strconv.Atoi(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", encoded)
+	}
+	return NewNumberValue(n), nil
+}
+
+// restoredObject finds the object a save refers to. Blueprints are loaded
+// again, clones only exist until a restart and become 0 without a snapshot.
+func (vm *VirtualMachine) restoredObject(name string) (o *Object) {
+	if o = vm.FindObject(name); o != nil || strings.Contains(name, cloneSeparator) {
+		return o
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Warn("Cannot load saved object", "object", name, "error", r)
+			o = nil
+		}
+	}()
+	return vm.LoadObject(name)
+}
+
+// fitsType tells whether a restored value can go into a variable of a type,
+// 0 fits everywhere like an unset object.
+func fitsType(value Value, t compiler.Type) bool {
+	if n, ok := value.(NumberValue); ok && n.Value == 0 {
+		return true
+	}
+	switch value.(type) {
+	case *StringValue:
+		return t == compiler.StringType
+	case ObjectValue:
+		return t == compiler.ObjectType
+	default:
+		return t == compiler.IntType
+	}
+}
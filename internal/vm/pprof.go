@@ -0,0 +1,155 @@
+package vm
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// Field numbers from profile.proto in github.com/google/pprof, the encoder
+// below writes just the parts go tool pprof needs.
+const (
+	pprofSampleType  = 1
+	pprofSample      = 2
+	pprofLocation    = 4
+	pprofFunction    = 5
+	pprofStringTable = 6
+	pprofTimeNanos   = 9
+	pprofDuration    = 10
+	pprofPeriodType  = 11
+	pprofPeriod      = 12
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationId = 1
+	pprofSampleValue      = 2
+
+	pprofLocationId   = 1
+	pprofLocationLine = 4
+
+	pprofLineFunctionId = 1
+	pprofLineLine       = 2
+
+	pprofFunctionId       = 1
+	pprofFunctionName     = 2
+	pprofFunctionFileName = 4
+)
+
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	b.data = binary.AppendUvarint(b.data, v)
+}
+
+func (b *protoBuffer) key(field int, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(v)
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *protoBuffer) message(field int, m *protoBuffer) {
+	b.bytes(field, m.data)
+}
+
+func (b *protoBuffer) packed(field int, values []uint64) {
+	var p protoBuffer
+	for _, v := range values {
+		p.varint(v)
+	}
+	b.bytes(field, p.data)
+}
+
+type stringTable struct {
+	strings []string
+	index   map[string]uint64
+}
+
+func (t *stringTable) id(s string) uint64 {
+	if t.index == nil {
+		t.strings, t.index = []string{""}, map[string]uint64{"": 0}
+	}
+	id, ok := t.index[s]
+	if !ok {
+		id = uint64(len(t.strings))
+		t.strings = append(t.strings, s)
+		t.index[s] = id
+	}
+	return id
+}
+
+func valueType(strings *stringTable, typ string, unit string) *protoBuffer {
+	var b protoBuffer
+	b.uint(pprofValueTypeType, strings.id(typ))
+	b.uint(pprofValueTypeUnit, strings.id(unit))
+	return &b
+}
+
+// WritePprof writes the samples as a gzipped pprof profile with instruction
+// counts and exclusive CPU time per call stack.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	var strings stringTable
+	var profile protoBuffer
+	profile.message(pprofSampleType, valueType(&strings, "instructions", "count"))
+	profile.message(pprofSampleType, valueType(&strings, "cpu", "nanoseconds"))
+
+	for _, s := range p.samples {
+		var sample protoBuffer
+		ids := make([]uint64, len(s.stack))
+		for i, id := range s.stack {
+			ids[i] = uint64(id) + 1
+		}
+		sample.packed(pprofSampleLocationId, ids)
+		sample.packed(pprofSampleValue, []uint64{uint64(s.instructions), uint64(s.nanoseconds)})
+		profile.message(pprofSample, &sample)
+	}
+
+	functionIds := make(map[profileLocation]uint64)
+	for id, l := range p.locations {
+		f := profileLocation{file: l.file, function: l.function}
+		if _, ok := functionIds[f]; !ok {
+			functionIds[f] = uint64(len(functionIds)) + 1
+			var function protoBuffer
+			function.uint(pprofFunctionId, functionIds[f])
+			function.uint(pprofFunctionName, strings.id(f.function))
+			function.uint(pprofFunctionFileName, strings.id(f.file))
+			profile.message(pprofFunction, &function)
+		}
+
+		var line protoBuffer
+		line.uint(pprofLineFunctionId, functionIds[f])
+		line.uint(pprofLineLine, uint64(l.line))
+		var location protoBuffer
+		location.uint(pprofLocationId, uint64(id)+1)
+		location.message(pprofLocationLine, &line)
+		profile.message(pprofLocation, &location)
+	}
+
+	profile.message(pprofPeriodType, valueType(&strings, "cpu", "nanoseconds"))
+	profile.uint(pprofPeriod, 1)
+	profile.uint(pprofTimeNanos, uint64(p.started.UnixNano()))
+	profile.uint(pprofDuration, uint64(p.duration()))
+	for _, s := range strings.strings {
+		profile.bytes(pprofStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
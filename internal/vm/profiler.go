@@ -0,0 +1,177 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type profileLocation struct {
+	file     string
+	function string
+	line     int
+}
+
+func (l profileLocation) String() string {
+	return l.file + ":" + strconv.Itoa(l.line)
+}
+
+type profileSample struct {
+	stack        []int
+	instructions int64
+	nanoseconds  int64
+}
+
+// Profiler measures every operation run while it is attached and charges its
+// time to the call stack it ran in. Calls are charged only for the time not
+// already spent in the called method, so the time of a sample is exclusive.
+type Profiler struct {
+	locations   []profileLocation
+	locationIds map[profileLocation]int
+	samples     map[string]*profileSample
+	recorded    time.Duration
+	started     time.Time
+	stopped     time.Time
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		locationIds: make(map[profileLocation]int),
+		samples:     make(map[string]*profileSample),
+		started:     time.Now(),
+	}
+}
+
+// SetProfiler attaches p to the calls started from now on, nil detaches it.
+// It has to happen on the VM goroutine, as do reports of an attached profiler.
+func (vm *VirtualMachine) SetProfiler(p *Profiler) {
+	if vm.profiler != nil {
+		vm.profiler.stopped = time.Now()
+	}
+	vm.profiler = p
+}
+
+func (vm *VirtualMachine) GetProfiler() *Profiler {
+	return vm.profiler
+}
+
+func (p *Profiler) execute(ef *ExecutionFrame) {
+	pc := ef.programCounter
+	mark := p.recorded
+	start := time.Now()
+	ef.program[pc].Execute(ef)
+	self := time.Since(start) - (p.recorded - mark)
+	p.recorded += self
+	p.record(ef, pc, self)
+}
+
+func (p *Profiler) locationId(l profileLocation) int {
+	id, ok := p.locationIds[l]
+	if !ok {
+		id = len(p.locations)
+		p.locations = append(p.locations, l)
+		p.locationIds[l] = id
+	}
+	return id
+}
+
+func (p *Profiler) record(ef *ExecutionFrame, pc int, self time.Duration) {
+	stack := make([]int, 0, ef.depth+1)
+	var key strings.Builder
+	for f := ef; f != nil && f.method != nil; f = f.parent {
+		line := f.method.lineAt(f.programCounter)
+		if f == ef {
+			line = f.method.lineAt(pc)
+		}
+		id := p.locationId(profileLocation{f.method.file, f.method.name, line})
+		stack = append(stack, id)
+		key.WriteString(strconv.Itoa(id))
+		key.WriteByte(' ')
+	}
+	s, ok := p.samples[key.String()]
+	if !ok {
+		s = &profileSample{stack: stack}
+		p.samples[key.String()] = s
+	}
+	s.instructions++
+	s.nanoseconds += int64(self)
+}
+
+func (p *Profiler) duration() time.Duration {
+	if p.stopped.IsZero() {
+		return time.Since(p.started)
+	}
+	return p.stopped.Sub(p.started)
+}
+
+type ProfileEntry struct {
+	Function     string
+	Location     string
+	Instructions int64
+	Flat         time.Duration
+	Cumulative   time.Duration
+}
+
+// Top aggregates the samples per source line and returns the n lines with
+// the most exclusive time, all of them when n is 0.
+func (p *Profiler) Top(n int) []ProfileEntry {
+	entries := make([]ProfileEntry, len(p.locations))
+	for id, l := range p.locations {
+		entries[id] = ProfileEntry{Function: l.function, Location: l.String()}
+	}
+	for _, s := range p.samples {
+		leaf := &entries[s.stack[0]]
+		leaf.Instructions += s.instructions
+		leaf.Flat += time.Duration(s.nanoseconds)
+		seen := make(map[int]bool)
+		for _, id := range s.stack {
+			if !seen[id] {
+				seen[id] = true
+				entries[id].Cumulative += time.Duration(s.nanoseconds)
+			}
+		}
+	}
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].Flat != entries[b].Flat {
+			return entries[a].Flat > entries[b].Flat
+		}
+		return entries[a].Location < entries[b].Location
+	})
+	if n > 0 && n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+func (p *Profiler) WriteTop(w io.Writer, n int) error {
+	var instructions int64
+	for _, s := range p.samples {
+		instructions += s.instructions
+	}
+	top := p.Top(n)
+	if _, err := fmt.Fprintf(w, "Showing top %d of %d lines, %s in %d instructions over %s\n",
+		len(top), len(p.locations), p.recorded, instructions, p.duration().Round(time.Millisecond)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%10s %6s %10s %6s %8s  %s\n", "flat", "flat%", "cum", "cum%", "instr", "function location"); err != nil {
+		return err
+	}
+	for _, e := range top {
+		_, err := fmt.Fprintf(w, "%10s %5.1f%% %10s %5.1f%% %8d  %s %s\n",
+			e.Flat, percent(e.Flat, p.recorded), e.Cumulative, percent(e.Cumulative, p.recorded), e.Instructions, e.Function, e.Location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func percent(part time.Duration, total time.Duration) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
@@ -0,0 +1,289 @@
+package vm
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	SchedulerTick     = 100 * time.Millisecond
+	HeartBeatInterval = 2 * time.Second
+	HeartBeatMethod   = "HeartBeat"
+	wheelSize         = 512
+)
+
+// Clock is where the scheduler gets its time from, tests swap the system
+// clock for a ManualClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var SystemClock Clock = systemClock{}
+
+type manualTimer struct {
+	at      time.Time
+	channel chan time.Time
+}
+
+// ManualClock only moves when Advance is called.
+type ManualClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	channel := make(chan time.Time, 1)
+	if d <= 0 {
+		channel <- c.now
+	} else {
+		c.timers = append(c.timers, manualTimer{c.now.Add(d), channel})
+	}
+	return channel
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.channel <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+type scheduledCall struct {
+	object    *Object
+	method    string
+	arguments []Value
+	context   ContextProvider
+	due       int64
+	heartBeat bool
+	removed   bool
+}
+
+// Scheduler keeps delayed calls and heartbeats in a hashed timer wheel of
+// SchedulerTick slots, calls further away than one turn of the wheel wait in
+// their slot for the right round. It is only used on the VM goroutine.
+type Scheduler struct {
+	clock      Clock
+	start      time.Time
+	tick       int64
+	wheel      [wheelSize][]*scheduledCall
+	heartBeats map[*Object]*scheduledCall
+}
+
+func newScheduler(clock Clock) *Scheduler {
+	return &Scheduler{clock: clock, start: clock.Now(), heartBeats: make(map[*Object]*scheduledCall)}
+}
+
+func (s *Scheduler) currentTick() int64 {
+	return max(s.tick, int64(s.clock.Now().Sub(s.start)/SchedulerTick))
+}
+
+func ticks(d time.Duration) int64 {
+	return max(int64((d+SchedulerTick-1)/SchedulerTick), 1)
+}
+
+func (s *Scheduler) add(c *scheduledCall) {
+	slot := c.due % wheelSize
+	s.wheel[slot] = append(s.wheel[slot], c)
+}
+
+// CallOut runs method on object after delay with the context it was
+// scheduled from.
+func (s *Scheduler) CallOut(object *Object, method string, delay time.Duration, arguments []Value, contextProvider ContextProvider) {
+	s.add(&scheduledCall{
+		object:    object,
+		method:    method,
+		arguments: arguments,
+		context:   contextProvider,
+		due:       s.currentTick() + ticks(delay),
+	})
+}
+
+// RemoveCallOut cancels the first pending call of method on object and
+// returns how long it still had to wait.
+func (s *Scheduler) RemoveCallOut(object *Object, method string) (time.Duration, bool) {
+	var found *scheduledCall
+	for _, slot := range s.wheel {
+		for _, c := range slot {
+			if !c.removed && !c.heartBeat && c.object == object && c.method == method && (found == nil || c.due < found.due) {
+				found = c
+			}
+		}
+	}
+	if found == nil {
+		return 0, false
+	}
+	found.removed = true
+	return s.remaining(found), true
+}
+
+// SetHeartBeat turns the HeartBeat calls of object on or off. Heartbeats are
+// not started by a player, they run in the machine's default context.
+func (s *Scheduler) SetHeartBeat(object *Object, enabled bool) {
+	c, ok := s.heartBeats[object]
+	switch {
+	case enabled && !ok:
+		c = &scheduledCall{object: object, method: HeartBeatMethod, heartBeat: true, due: s.currentTick() + ticks(HeartBeatInterval)}
+		s.heartBeats[object] = c
+		s.add(c)
+	case !enabled && ok:
+		c.removed = true
+		delete(s.heartBeats, object)
+	}
+}
+
+func (s *Scheduler) removeObject(object *Object) {
+	for _, slot := range s.wheel {
+		for _, c := range slot {
+			if c.object == object {
+				c.removed = true
+			}
+		}
+	}
+	delete(s.heartBeats, object)
+}
+
+// pending lists the delayed calls that still have to run, soonest first.
+func (s *Scheduler) pending() []*scheduledCall {
+	result := make([]*scheduledCall, 0)
+	for _, slot := range s.wheel {
+		for _, c := range slot {
+			if !c.removed && !c.heartBeat {
+				result = append(result, c)
+			}
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		return result[a].due < result[b].due
+	})
+	return result
+}
+
+func (s *Scheduler) remaining(c *scheduledCall) time.Duration {
+	return time.Duration(max(c.due-s.currentTick(), 0)) * SchedulerTick
+}
+
+func (s *Scheduler) HasHeartBeat(object *Object) bool {
+	_, ok := s.heartBeats[object]
+	return ok
+}
+
+// due moves the wheel up to the clock and returns the calls that came due,
+// in the order they were due. Heartbeats are scheduled again right away.
+func (s *Scheduler) due() []*scheduledCall {
+	result := make([]*scheduledCall, 0)
+	for target := s.currentTick(); s.tick < target; {
+		s.tick++
+		slot := s.tick % wheelSize
+		waiting := s.wheel[slot][:0]
+		var again []*scheduledCall
+		for _, c := range s.wheel[slot] {
+			switch {
+			case c.removed:
+			case c.due > s.tick:
+				waiting = append(waiting, c)
+			default:
+				result = append(result, c)
+				if c.heartBeat {
+					again = append(again, c)
+				}
+			}
+		}
+		s.wheel[slot] = waiting
+		for _, c := range again {
+			c.due += ticks(HeartBeatInterval)
+			s.add(c)
+		}
+	}
+	return result
+}
+
+type emptyContext struct{}
+
+func (emptyContext) GetObjectValueFromContext(string) *ObjectValue {
+	return nil
+}
+
+// RunDueCalls runs every call the scheduler has due. A failing call is
+// logged, a failing heartbeat is also turned off so it does not fail again
+// every interval.
+func (vm *VirtualMachine) RunDueCalls() {
+	for _, c := range vm.scheduler.due() {
+		if c.removed {
+			continue
+		}
+		context := c.context
+		if context == nil {
+			context = vm.defaultContext
+		}
+		if _, err := vm.Call(c.object, c.method, c.arguments, context); err != nil {
+			logger.Warn("Scheduled call failed", "object", c.object, "method", c.method, "error", err)
+			if c.heartBeat {
+				vm.scheduler.SetHeartBeat(c.object, false)
+			}
+		}
+	}
+}
+
+// SetDefaultContext sets what code not started by a player sees, like
+// heartbeats. Without one only driver is available.
+func (vm *VirtualMachine) SetDefaultContext(contextProvider ContextProvider) {
+	vm.defaultContext = contextProvider
+}
+
+func (vm *VirtualMachine) GetScheduler() *Scheduler {
+	return vm.scheduler
+}
+
+// SetClock replaces the scheduler's clock, pending calls are dropped. Call it
+// before Run.
+func (vm *VirtualMachine) SetClock(clock Clock) {
+	vm.scheduler = newScheduler(clock)
+}
+
+func (vm *VirtualMachine) runScheduler() {
+	for {
+		select {
+		case <-vm.stopped:
+			return
+		case <-vm.scheduler.clock.After(SchedulerTick):
+		}
+		select {
+		case <-vm.stopped:
+			return
+		case vm.commandChannel <- NewFuncCommand((*VirtualMachine).RunDueCalls):
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"goMud/internal/fsutil"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SnapshotFormatVersion = 2
+	snapshotHeader        = "# gmsl snapshot "
+)
+
+// Snapshot writes the whole object table to a single file: every blueprint
+// and clone with its variables, its names, its actions, what it contains,
+// its heartbeat and its pending delayed calls.
+//
+//	# gmsl snapshot 2
+//
+//	object "locations/room_a" 2
+//	variable visits 3
+//	name "armoury"
+//	action "north" "North"
+//	contains "obj/coin#5"
+//	heartbeat
+//	callout "Reset" 4m30s
+//	argument "quiet"
+//
+// Objects created from Go, like the players, and those of transient classes
+// are left out. The file is replaced atomically. It must run on the
+// goroutine that owns the machine.
+func (vm *VirtualMachine) Snapshot(path string) error {
+	var b strings.Builder
+	b.WriteString(snapshotHeader + strconv.Itoa(SnapshotFormatVersion) + "\n")
+	calls := make(map[*Object][]*scheduledCall)
+	for _, c := range vm.scheduler.pending() {
+		calls[c.object] = append(calls[c.object], c)
+	}
+	for _, o := range vm.Objects() {
+		if vm.transient[o.class.name] {
+			continue
+		}
+		b.WriteString("\nobject " + strconv.Quote(o.name) + " " + strconv.Itoa(o.id) + "\n")
+		for n, v := range o.class.variables {
+			b.WriteString("variable " + v.GetName() + " " + snapshotValue(o, v.GetName(), o.variables[n]) + "\n")
+		}
+		writeNames(&b, o, "")
+		for _, a := range o.actions {
+			b.WriteString("action " + strconv.Quote(a.verb) + " " + strconv.Quote(a.method) + "\n")
+		}
+		for _, i := range o.inventory {
+			if i.name != "" && !vm.transient[i.class.name] {
+				b.WriteString("contains " + strconv.Quote(i.name) + "\n")
+			}
+		}
+		if vm.scheduler.HasHeartBeat(o) {
+			b.WriteString("heartbeat\n")
+		}
+		for _, c := range calls[o] {
+			b.WriteString("callout " + strconv.Quote(c.method) + " " + vm.scheduler.remaining(c).String() + "\n")
+			for _, a := range c.arguments {
+				b.WriteString("argument " + snapshotValue(o, c.method, a) + "\n")
+			}
+		}
+	}
+	return fsutil.WriteFileAtomic(path, []byte(b.String()), 0o755)
+}
+
+// SetTransient keeps the blueprint and clones of a class out of snapshots
+// and out of the world restored from older ones, for objects whose state
+// only makes sense while a connection lasts.
+func (vm *VirtualMachine) SetTransient(class string) {
+	vm.transient[normalizeObjectName(class)] = true
+}
+
+// snapshotValue encodes a value, those that can not be saved, like a
+// reference to a player, become 0 so a single one does not lose the world.
+func snapshotValue(o *Object, name string, v Value) string {
+	encoded, err := encodeValue(v)
+	if err != nil {
+		logger.Warn("Value left out of the snapshot", "object", o.name, "name", name, "error", err)
+		return "0"
+	}
+	return encoded
+}
+
+type snapshotCall struct {
+	method    string
+	delay     time.Duration
+	arguments []string
+}
+
+type snapshotObject struct {
+	name      string
+	id        int
+	line      int
+	variables map[string]string
+	names     objectNames
+	actions   []action
+	contains  []string
+	heartBeat bool
+	calls     []*snapshotCall
+}
+
+// RestoreSnapshot recreates the objects of a snapshot, meant to run once on
+// boot before anyone is connected. Restored objects do not run their Create
+// hook, their variables, names, actions and contents come from the snapshot,
+// so a changed Create only affects objects made after the boot. Snapshots of
+// version 1 have no names or actions, their objects still run Create.
+// Objects whose class no longer loads are skipped and references to them
+// become 0. Delayed calls run with the default context, the one they were
+// scheduled with is gone.
+func (vm *VirtualMachine) RestoreSnapshot(path string) error {
+	records, version, err := readSnapshot(path)
+	if err != nil {
+		return err
+	}
+	records = slices.DeleteFunc(records, func(r *snapshotObject) bool {
+		class, _, _ := strings.Cut(r.name, cloneSeparator)
+		return vm.transient[class]
+	})
+	for _, r := range records {
+		vm.lastObjectId = max(vm.lastObjectId, r.id)
+	}
+
+	objects := make([]*Object, len(records))
+	for n, r := range records {
+		o, err := vm.restoreObject(r.name, r.id, version < 2)
+		if err != nil {
+			logger.Warn("Object left out of the restored world", "file", path, "line", r.line, "object", r.name, "error", err)
+			continue
+		}
+		objects[n] = o
+	}
+
+	for n, r := range records {
+		o := objects[n]
+		if o == nil {
+			continue
+		}
+		values := make(map[string]Value)
+		for name, encoded := range r.variables {
+			if values[name], err = vm.decodeValue(encoded); err != nil {
+				return fmt.Errorf("%s: object %s: %w", path, r.name, err)
+			}
+		}
+		setVariables(o, values, path)
+		if version >= 2 {
+			r.names.apply(o)
+			o.actions = r.actions
+		}
+		// what Create scheduled gives way to what the snapshot recorded
+		vm.scheduler.removeObject(o)
+		for _, name := range r.contains {
+			i := vm.FindObject(name)
+			if i == nil || i.environment == o || i == o || i.Contains(o) {
+				continue
+			}
+			i.moveInto(o)
+		}
+		if r.heartBeat {
+			vm.scheduler.SetHeartBeat(o, true)
+		}
+		for _, c := range r.calls {
+			arguments := make([]Value, len(c.arguments))
+			for a, encoded := range c.arguments {
+				if arguments[a], err = vm.decodeValue(encoded); err != nil {
+					return fmt.Errorf("%s: object %s: %w", path, r.name, err)
+				}
+			}
+			vm.scheduler.CallOut(o, c.method, c.delay, arguments, nil)
+		}
+	}
+	logger.Info("World restored", "file", path, "objects", len(records))
+	return nil
+}
+
+// restoreObject brings back a blueprint or a clone under its old name and
+// id, running Create only for old snapshots.
+func (vm *VirtualMachine) restoreObject(name string, id int, runCreate bool) (o *Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = RuntimeError(r)
+		}
+	}()
+	if o = vm.FindObject(name); o != nil {
+		return o, nil
+	}
+	path, _, _ := strings.Cut(name, cloneSeparator)
+	class := vm.getClass(path)
+	o = &Object{class: class, name: name, id: id, variables: class.newVariables()}
+	vm.objects[name] = o
+	if runCreate {
+		vm.create(o)
+	}
+	return o, nil
+}
+
+func readSnapshot(path string) (records []*snapshotObject, version int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	records = make([]*snapshotObject, 0)
+	var current *snapshotObject
+	var call *snapshotCall
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			if version, err = checkSnapshotHeader(text); err != nil {
+				return nil, 0, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(text, " ")
+		if keyword != "object" && current == nil {
+			return nil, 0, fmt.Errorf("%s:%d: %s outside of an object", path, line, keyword)
+		}
+		var err error
+		switch keyword {
+		case "object":
+			current = &snapshotObject{line: line, variables: make(map[string]string)}
+			call = nil
+			err = parseObjectLine(rest, current)
+			records = append(records, current)
+		case "variable":
+			name, value, found := strings.Cut(rest, " ")
+			if !found {
+				err = errors.New("variable without a value")
+			}
+			current.variables[name] = value
+		case "action":
+			var a action
+			a, err = parseActionLine(rest)
+			current.actions = append(current.actions, a)
+		case "contains":
+			var name string
+			name, err = strconv.Unquote(rest)
+			current.contains = append(current.contains, name)
+		case "heartbeat":
+			current.heartBeat = true
+		case "callout":
+			call = &snapshotCall{}
+			err = parseCallLine(rest, call)
+			current.calls = append(current.calls, call)
+		case "argument":
+			if call == nil {
+				err = errors.New("argument outside of a callout")
+				break
+			}
+			call.arguments = append(call.arguments, rest)
+		default:
+			var ok bool
+			if ok, err = current.names.parse(keyword, rest); !ok {
+				err = errors.New("unknown entry " + keyword)
+			}
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	return records, version, scanner.Err()
+}
+
+func checkSnapshotHeader(line string) (int, error) {
+	if !strings.HasPrefix(line, snapshotHeader) {
+		return 0, errors.New("not a snapshot")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(line, snapshotHeader))
+	if err != nil {
+		return 0, errors.New("invalid snapshot version")
+	}
+	if version > SnapshotFormatVersion {
+		return 0, fmt.Errorf("snapshot version %d is newer than %d", version, SnapshotFormatVersion)
+	}
+	return version, nil
+}
+
+// Names are quoted and may hold spaces, the id or delay follows the last
+// quote.
+func splitQuoted(s string) (string, string, error) {
+	end := strings.LastIndexByte(s, '"')
+	if end < 0 {
+		return "", "", errors.New("missing quoted name")
+	}
+	name, err := strconv.Unquote(s[:end+1])
+	return name, strings.TrimSpace(s[end+1:]), err
+}
+
+func parseObjectLine(s string, o *snapshotObject) (err error) {
+	var id string
+	if o.name, id, err = splitQuoted(s); err != nil {
+		return err
+	}
+	o.id, err = strconv.Atoi(id)
+	return err
+}
+
+// parseActionLine reads the quoted verb and method of an action.
+func parseActionLine(s string) (a action, err error) {
+	a.verb, a.method, err = splitTwoQuoted(s)
+	return a, err
+}
+
+func parseCallLine(s string, c *snapshotCall) (err error) {
+	var delay string
+	if c.method, delay, err = splitQuoted(s); err != nil {
+		return err
+	}
+	c.delay, err = time.ParseDuration(delay)
+	return err
+}
+
+// SetAutosave makes Run snapshot the world to path every interval and
+// Shutdown snapshot it one last time. Call it before Run.
+func (vm *VirtualMachine) SetAutosave(path string, interval time.Duration) {
+	vm.snapshotPath = path
+	vm.autosave = interval
+}
+
+func (vm *VirtualMachine) saveSnapshot() {
+	start := time.Now()
+	if err := vm.Snapshot(vm.snapshotPath); err != nil {
+		logger.Error("Snapshot failed", "file", vm.snapshotPath, "error", err)
+		return
+	}
+	logger.Info("World saved", "file", vm.snapshotPath, "elapsed", time.Since(start))
+}
+
+func (vm *VirtualMachine) runAutosave() {
+	for {
+		select {
+		case <-vm.stopped:
+			return
+		case <-vm.scheduler.clock.After(vm.autosave):
+		}
+		select {
+		case <-vm.stopped:
+			return
+		case vm.commandChannel <- NewFuncCommand((*VirtualMachine).saveSnapshot):
+		}
+	}
+}
+
+// Shutdown stops the machine after the commands already queued, taking a
+// last snapshot when autosave is on. It returns once the machine stopped.
+func (vm *VirtualMachine) Shutdown() {
+	vm.commandChannel <- NewFuncCommand(func(vm *VirtualMachine) {
+		if vm.snapshotPath != "" {
+			vm.saveSnapshot()
+		}
+		(&StopCommand{}).Handle(vm)
+	})
+	<-vm.stopped
+}
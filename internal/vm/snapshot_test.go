@@ -0,0 +1,148 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var snapshotClasses = map[string]string{
+	"hall.gms": `package main
+
+var visits int
+var label string
+var coin object
+
+func Create() {
+    driver.SetName("hall")
+    driver.AddAdjective("long")
+    driver.AddAction("north" "North")
+    driver.SetHeartBeat(1)
+    driver.CallOutWith("Reset" 30 "quiet" 2)
+    coin = driver.CloneObject("coin")
+    driver.MoveObject(coin this)
+}
+
+func HeartBeat() {
+    visits = visits + 1
+}
+
+func Reset(mode string n int) {
+    label = mode + " " + n
+}
+
+func North(rest string) int {
+    return 1
+}
+`,
+	"coin.gms": `package main
+
+var value int
+
+func Create() {
+    driver.SetName("coin")
+    driver.AddAdjective("gold")
+    value = 7
+}
+`,
+}
+
+func newSnapshotMachine(t *testing.T, mudlib string, clock *ManualClock) *VirtualMachine {
+	t.Helper()
+	machine := NewVirtualMachine(mudlib)
+	machine.SetClock(clock)
+	return machine
+}
+
+func variable(o *Object, name string) Value {
+	for n, v := range o.class.variables {
+		if v.GetName() == name {
+			return o.variables[n]
+		}
+	}
+	return nil
+}
+
+// TestSnapshotRoundTrip snapshots a world part way through its delayed
+// calls and checks a fresh machine restores it as it was.
+func TestSnapshotRoundTrip(t *testing.T) {
+	mudlib := t.TempDir()
+	for name, source := range snapshotClasses {
+		if err := os.WriteFile(filepath.Join(mudlib, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	clock := NewManualClock(start)
+	machine := newSnapshotMachine(t, mudlib, clock)
+	machine.LoadObject("hall")
+	clock.Advance(10 * time.Second)
+	machine.RunDueCalls()
+	path := filepath.Join(t.TempDir(), "world.snapshot")
+	if err := machine.Snapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredClock := NewManualClock(start)
+	restored := newSnapshotMachine(t, mudlib, restoredClock)
+	if err := restored.RestoreSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range machine.Objects() {
+		r := restored.FindObject(o.name)
+		if r == nil {
+			t.Fatalf("%s was not restored", o.name)
+		}
+		if r.id != o.id {
+			t.Errorf("%s id = %d, want %d", o.name, r.id, o.id)
+		}
+		if !slices.Equal(r.nouns, o.nouns) || !slices.Equal(r.adjectives, o.adjectives) {
+			t.Errorf("%s names = %v %v, want %v %v", o.name, r.adjectives, r.nouns, o.adjectives, o.nouns)
+		}
+		if !slices.Equal(r.actions, o.actions) {
+			t.Errorf("%s actions = %v, want %v", o.name, r.actions, o.actions)
+		}
+		for _, v := range o.class.variables {
+			if got, want := variable(r, v.GetName()).String(), variable(o, v.GetName()).String(); got != want {
+				t.Errorf("%s.%s = %s, want %s", o.name, v.GetName(), got, want)
+			}
+		}
+	}
+	if len(restored.Objects()) != len(machine.Objects()) {
+		t.Errorf("restored %d objects, want %d", len(restored.Objects()), len(machine.Objects()))
+	}
+
+	hall := restored.FindObject("hall")
+	coin := restored.FindObject(variable(machine.FindObject("hall"), "coin").(ObjectValue).value.name)
+	if coin == nil || coin.Environment() != hall || !slices.Equal(hall.AllInventory(), []*Object{coin}) {
+		t.Fatalf("hall contains %v, want the coin", hall.AllInventory())
+	}
+	if ref, ok := variable(hall, "coin").(ObjectValue); !ok || ref.value != coin {
+		t.Errorf("hall.coin = %v, want the restored coin", variable(hall, "coin"))
+	}
+	if got := variable(hall, "visits").String(); got != "5" {
+		t.Errorf("visits = %s after 10 seconds of heartbeats, want 5", got)
+	}
+	if !restored.scheduler.HasHeartBeat(hall) {
+		t.Error("the hall lost its heartbeat")
+	}
+
+	// 20 of the 30 seconds of the call_out were left at the snapshot
+	restoredClock.Advance(19 * time.Second)
+	restored.RunDueCalls()
+	if got := variable(hall, "label").String(); got != "" {
+		t.Fatalf("Reset ran after 19 seconds, label = %q", got)
+	}
+	restoredClock.Advance(time.Second)
+	restored.RunDueCalls()
+	if got := variable(hall, "label").String(); got != "quiet 2" {
+		t.Errorf("label = %q after the call_out, want \"quiet 2\"", got)
+	}
+	if got := variable(hall, "visits").String(); got != "15" {
+		t.Errorf("visits = %s, want 15 after the restored heartbeat ran 10 more times", got)
+	}
+}
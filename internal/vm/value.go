@@ -15,6 +15,11 @@ type Value interface {
 	Modulo(v Value) Value
 }
 
+// IsTruthy tells whether an if statement would take a value as true.
+func IsTruthy(v Value) bool {
+	return v != nil && v.isTruthy()
+}
+
 type StringValue struct {
 	Value string
 }
@@ -63,11 +68,14 @@ type ObjectValue struct {
 }
 
 func (o ObjectValue) isTruthy() bool {
-	return o.value != nil
+	return o.value != nil && !o.value.destructed
 }
 
 func (o ObjectValue) equalValue(b Value) Value {
-	if ov, ok := b.(*ObjectValue); ok {
+	switch ov := b.(type) {
+	case ObjectValue:
+		return BooleanValue{Value: o.value == ov.value}
+	case *ObjectValue:
 		return BooleanValue{Value: o.value == ov.value}
 	}
 	return BooleanValue{Value: false}
@@ -90,7 +98,14 @@ func (o ObjectValue) Divide(v Value) Value {
 }
 
 func (o ObjectValue) String() string {
-	return "Object"
+	if o.value == nil || o.value.name == "" {
+		return "Object"
+	}
+	return o.value.name
+}
+
+func (o ObjectValue) GetObject() *Object {
+	return o.value
 }
 
 func (o ObjectValue) Multiply(v Value) Value {
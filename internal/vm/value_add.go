@@ -9,6 +9,7 @@ import "log"
 // NumberValue | concatenate(a.String(),b) | unsupportedAddition(a,b) | or(a.isTruthy(), b)       | a + b
 
 func add(a Value, b Value) Value {
+	a, b = dereference(a), dereference(b)
 	switch a.(type) {
 	case StringValue:
 		switch b.(type) {
@@ -49,6 +50,15 @@ func add(a Value, b Value) Value {
 	return nil
 }
 
+// dereference turns the *StringValue strings are pushed as into the
+// StringValue the table above works with.
+func dereference(v Value) Value {
+	if s, ok := v.(*StringValue); ok {
+		return *s
+	}
+	return v
+}
+
 func or(a Value, b Value) Value {
 	return BooleanValue{Value: a.isTruthy() || b.isTruthy()}
 }
@@ -1,6 +1,23 @@
 package vm
 
-import "log"
+import (
+	"context"
+	"goMud/internal/logging"
+	"time"
+)
+
+var logger = logging.Logger(logging.VM)
+
+// trace logs a single step of execution, it is only written when the vm
+// subsystem runs at the trace level. Callers check tracing first so that the
+// arguments are not built for every operation.
+func trace(message string, args ...any) {
+	logger.Log(context.Background(), logging.LevelTrace, message, args...)
+}
+
+func tracing() bool {
+	return logger.Enabled(context.Background(), logging.LevelTrace)
+}
 
 type Command interface {
 	Handle(vm *VirtualMachine)
@@ -9,13 +26,13 @@ type Command interface {
 type StopCommand struct{}
 
 type MethodCallCommand struct {
-	object          Object
+	object          *Object
 	method          string
 	arguments       []Value
 	contextProvider ContextProvider
 }
 
-func NewMethodCallCommand(object Object, method string, arguments []Value, contextProvider ContextProvider) *MethodCallCommand {
+func NewMethodCallCommand(object *Object, method string, arguments []Value, contextProvider ContextProvider) *MethodCallCommand {
 	return &MethodCallCommand{
 		object:          object,
 		method:          method,
@@ -28,33 +45,83 @@ func (c *MethodCallCommand) Handle(vm *VirtualMachine) {
 	vm.execute(c.object, c.method, c.arguments, c.contextProvider)
 }
 
+type FuncCommand struct {
+	f func(vm *VirtualMachine)
+}
+
+func NewFuncCommand(f func(vm *VirtualMachine)) *FuncCommand {
+	return &FuncCommand{f}
+}
+
+func (c *FuncCommand) Handle(vm *VirtualMachine) {
+	c.f(vm)
+}
+
 func (c *StopCommand) Handle(vm *VirtualMachine) {
-	close(vm.commandChannel)
-	log.Println("VM stopped")
+	close(vm.stopped)
+	logger.Info("VM stopped")
 }
 
 type VirtualMachine struct {
 	commandChannel chan Command
 	classes        map[string]Class
+	mudlibPath     string
+	debugger       *Debugger
+	profiler       *Profiler
+	scheduler      *Scheduler
+	driver         *Object
+	stopped        chan struct{}
+	defaultContext ContextProvider
+	objects        map[string]*Object
+	lastObjectId   int
+	dataPath       string
+	snapshotPath   string
+	autosave       time.Duration
+	// transient are the classes left out of snapshots.
+	transient map[string]bool
 }
 
 var instance *VirtualMachine
 
+// NewVirtualMachine creates a machine with its own class cache loading from
+// mudlibPath. The server uses the shared one from GetVirtualMachine, separate
+// machines keep tests and tools from seeing each other's classes.
+func NewVirtualMachine(mudlibPath string) *VirtualMachine {
+	vm := &VirtualMachine{
+		commandChannel: make(chan Command),
+		classes:        make(map[string]Class),
+		mudlibPath:     mudlibPath,
+		scheduler:      newScheduler(SystemClock),
+		stopped:        make(chan struct{}),
+		defaultContext: emptyContext{},
+		objects:        make(map[string]*Object),
+		dataPath:       DefaultDataPath,
+		transient:      make(map[string]bool),
+	}
+	vm.driver = newDriverObject(vm)
+	return vm
+}
+
 func GetVirtualMachine() *VirtualMachine {
 	if instance == nil {
-		instance = &VirtualMachine{
-			commandChannel: make(chan Command),
-			classes:        make(map[string]Class),
-		}
+		instance = NewVirtualMachine("mudlib")
 	}
 	return instance
 }
 
 func (vm *VirtualMachine) Run() {
-	log.Println("VM started")
+	logger.Info("VM started")
+	go vm.runScheduler()
+	if vm.snapshotPath != "" && vm.autosave > 0 {
+		go vm.runAutosave()
+	}
 	for {
-		command := <-vm.commandChannel
-		command.Handle(vm)
+		select {
+		case command := <-vm.commandChannel:
+			command.Handle(vm)
+		case <-vm.stopped:
+			return
+		}
 	}
 }
 
@@ -64,23 +131,26 @@ func (vm *VirtualMachine) Stop() {
 
 func (vm *VirtualMachine) getClass(name string) Class {
 	if _, ok := vm.classes[name]; !ok {
-		vm.classes[name] = *newClass(name)
+		vm.classes[name] = *newClass(vm.mudlibPath, name)
 	}
 
 	return vm.classes[name]
 }
 
-func (vm *VirtualMachine) execute(object Object, method string, arguments []Value, contextProvider ContextProvider) {
+func (vm *VirtualMachine) execute(object *Object, method string, arguments []Value, contextProvider ContextProvider) {
 	ef := NewExecutionFrame(contextProvider)
+	ef.debugger = vm.debugger
+	ef.profiler = vm.profiler
+	ef.machine = vm
 
-	calleeObjectValue := *NewObjectValue(&object)
+	calleeObjectValue := *NewObjectValue(object)
 	methodValue := NewStringValue(method)
 
 	for _, arg := range arguments {
 		ef.valueStack.push(arg)
 	}
 
-	ef.call(calleeObjectValue, methodValue)
+	ef.call(calleeObjectValue, methodValue, len(arguments))
 }
 
 func GetCommandChannel() chan Command {